@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"monkey/token"
 	"strings"
 )
@@ -10,6 +11,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -32,6 +34,12 @@ func (p *Program) TokenLiteral() string {
 	}
 	return ""
 }
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
 
 type LetStatement struct {
 	Value Expression
@@ -43,6 +51,9 @@ func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
 
 type Identifier struct {
 	Value string
@@ -53,6 +64,9 @@ func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
 
 type ReturnStatement struct {
 	ReturnValue Expression
@@ -63,6 +77,77 @@ func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
+type ThrowStatement struct {
+	Value Expression
+	Token token.Token // the 'throw' token
+}
+
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+type WhileStatement struct {
+	Condition Expression
+	Body      *BlockStatement
+	Token     token.Token // the 'while' token
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+func (ws *WhileStatement) Pos() token.Position {
+	return ws.Token.Pos
+}
+
+// ForStatement runs Body once for each element of Iterable, bound to
+// Variable.
+type ForStatement struct {
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+	Token    token.Token // the 'for' token
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
 
 type ExpressionStatement struct {
 	Expression Expression
@@ -73,6 +158,9 @@ func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
 
 type BlockStatement struct {
 	Statements []Statement
@@ -83,6 +171,9 @@ func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
 
 type IfExpression struct {
 	Condition Expression
@@ -95,31 +186,77 @@ func (ie *IfExpression) expressionNode() {}
 func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
+type TryExpression struct {
+	Block      *BlockStatement
+	CatchParam *Identifier     // nil when there is no catch clause
+	Catch      *BlockStatement // nil when there is no catch clause
+	Finally    *BlockStatement // nil when there is no finally clause
+	Token      token.Token     // the 'try' token
+}
+
+func (te *TryExpression) expressionNode() {}
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+func (te *TryExpression) Pos() token.Position {
+	return te.Token.Pos
+}
+
+type ImportExpression struct {
+	Path  Expression
+	Token token.Token // the 'import' token
+}
+
+func (ie *ImportExpression) expressionNode() {}
+func (ie *ImportExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+func (ie *ImportExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
 
 type FunctionLiteral struct {
 	Parameters []*Identifier
-	Body       *BlockStatement
-	Token      token.Token // The 'fn' token
+	// Default values of Parameters, nil for those without one. Only trailing
+	// parameters have defaults, Defaults is empty if none does
+	Defaults []Expression
+	Rest     *Identifier // Collects the arguments after Parameters, if set
+	Body     *BlockStatement
+	Name     string      // Set when the literal is bound with `let`
+	Token    token.Token // The 'fn' token
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
 func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
 
 type CallExpression struct {
 	Function  Expression
 	Arguments []Expression
 	Token     token.Token
+	// Set on calls whose result is the result of the enclosing function
+	Tail bool
 }
 
 func (ce *CallExpression) expressionNode() {}
 func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos
+}
 
 type IntegerLiteral struct {
 	Value int64
+	Big   *big.Int // Set instead of Value for literals that don't fit in 64 bits
 	Token token.Token
 }
 
@@ -127,6 +264,22 @@ func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
 
 type StringLiteral struct {
 	Value string
@@ -137,6 +290,9 @@ func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Pos
+}
 
 type PrefixExpression struct {
 	Right    Expression
@@ -148,6 +304,9 @@ func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
 
 type InfixExpression struct {
 	Left     Expression
@@ -160,6 +319,9 @@ func (pe *InfixExpression) expressionNode() {}
 func (pe *InfixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
+func (pe *InfixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
 
 type Boolean struct {
 	Value bool
@@ -170,6 +332,9 @@ func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
 
 type ArrayLiteral struct {
 	Elements []Expression
@@ -180,6 +345,9 @@ func (al *ArrayLiteral) expressionNode() {}
 func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
 
 type IndexExpression struct {
 	Left  Expression
@@ -191,16 +359,78 @@ func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
+// MemberExpression is a shorthand for indexing with a string, Left.Member
+// means Left["Member"].
+type MemberExpression struct {
+	Left   Expression
+	Member *Identifier
+	Token  token.Token // the '.' token
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+func (me *MemberExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+// AssignExpression stores Value in Target, an identifier, index or member
+// expression. Operator is "=" or a compound operator such as "+=", which
+// combines the current value of Target with Value.
+type AssignExpression struct {
+	Target   Expression
+	Operator string
+	Value    Expression
+	Token    token.Token // the assignment operator token
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos
+}
+
+// SpreadExpression splices the elements of Value into the call arguments or
+// array literal it appears in, or its pairs into a hash literal.
+type SpreadExpression struct {
+	Value Expression
+	Token token.Token // the '...' token
+}
+
+func (se *SpreadExpression) expressionNode() {}
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+func (se *SpreadExpression) Pos() token.Position {
+	return se.Token.Pos
+}
 
 type HashLiteral struct {
-	Pairs map[Expression]Expression
-	Token token.Token // the '{' token
+	Pairs []HashLiteralPair // In source order
+	Token token.Token       // the '{' token
+}
+
+// HashLiteralPair is a key and its value, or a spread hash, in which case Key
+// is a *SpreadExpression and Value is nil.
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode() {}
 func (hl *HashLiteral) TokenLiteral() string {
 	return hl.Token.Literal
 }
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
 
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -240,6 +470,34 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+func (ws *WhileStatement) String() string {
+	return "while" + ws.Condition.String() + " " + ws.Body.String()
+}
+
+func (fs *ForStatement) String() string {
+	return "for (" + fs.Variable.String() + " in " + fs.Iterable.String() + ") " + fs.Body.String()
+}
+
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -273,23 +531,60 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
-func (fl *FunctionLiteral) String() string {
+func (te *TryExpression) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(te.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
 	}
 
+	return out.String()
+}
+
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " " + ie.Path.String()
+}
+
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParameterList(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(") ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// ParameterList formats the parameters of a function as they are written
+// between its parentheses.
+func ParameterList(params []*Identifier, defaults []Expression, rest *Identifier) string {
+	list := []string{}
+	for i, p := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			list = append(list, p.String()+" = "+defaults[i].String())
+		} else {
+			list = append(list, p.String())
+		}
+	}
+	if rest != nil {
+		list = append(list, "..."+rest.String())
+	}
+	return strings.Join(list, ", ")
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -314,6 +609,10 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
@@ -372,12 +671,28 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+func (me *MemberExpression) String() string {
+	return "(" + me.Left.String() + "." + me.Member.String() + ")"
+}
+
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+func (se *SpreadExpression) String() string {
+	return se.TokenLiteral() + se.Value.String()
+}
+
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s:%s", key, value))
+	for _, pair := range hl.Pairs {
+		if pair.Value == nil {
+			pairs = append(pairs, pair.Key.String())
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s:%s", pair.Key, pair.Value))
 	}
 
 	out.WriteString("{")
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"strings"
+)
+
+// evalAssignExpression stores the value in the target and returns it. The
+// target's container and index are evaluated once, before the value. Errors,
+// returns and loop signals from any of them end the assignment.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		value := evalAssignedValue(node, func() object.Object {
+			return evalIdentifier(target, env)
+		}, env)
+		if interrupts(value) {
+			return value
+		}
+
+		if !env.Assign(target.Value, value) {
+			return newError("cannot assign to undeclared identifier: %s", target.Value)
+		}
+		return value
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if interrupts(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if interrupts(index) {
+			return index
+		}
+		return evalIndexAssignment(node, left, index, env)
+
+	case *ast.MemberExpression:
+		left := Eval(target.Left, env)
+		if interrupts(left) {
+			return left
+		}
+		return evalIndexAssignment(node, left, &object.String{Value: target.Member.Value}, env)
+
+	default:
+		return newError("cannot assign to %s", node.Target)
+	}
+}
+
+func evalIndexAssignment(node *ast.AssignExpression, left, index object.Object, env *object.Environment) object.Object {
+	value := evalAssignedValue(node, func() object.Object {
+		return evalIndexExpression(left, index)
+	}, env)
+	if interrupts(value) {
+		return value
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		array := left.(*object.Array)
+		integer, ok := index.(*object.Integer)
+		if !ok || integer.Value < 0 || integer.Value >= int64(len(array.Elements)) {
+			return newError("index out of range: %s", index.Inspect())
+		}
+		array.Elements[integer.Value] = value
+	case left.Type() == object.HASH_OBJ:
+		if !object.Hashable(index) {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.(*object.Hash).Set(index, value)
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
+
+// evalAssignedValue evaluates the value of an assignment. Compound assignments
+// apply their operator to the current value of the target, read before the
+// value is evaluated.
+func evalAssignedValue(node *ast.AssignExpression, current func() object.Object, env *object.Environment) object.Object {
+	if node.Operator == "=" {
+		return Eval(node.Value, env)
+	}
+
+	left := current()
+	if interrupts(left) {
+		return left
+	}
+	right := Eval(node.Value, env)
+	if interrupts(right) {
+		return right
+	}
+
+	return evalInfixExpression(strings.TrimSuffix(node.Operator, "="), left, right)
+}
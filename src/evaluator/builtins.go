@@ -3,15 +3,15 @@ package evaluator
 import (
 	"fmt"
 	"monkey/object"
+	"sort"
+	"strings"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
+		Name:  "len",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
 			switch arg := args[0].(type) {
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
@@ -23,13 +23,11 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"first": &object.Builtin{
+		Name:  "first",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgType("first", args[0], object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -40,13 +38,11 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": &object.Builtin{
+		Name:  "last",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgType("last", args[0], object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -57,13 +53,11 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": &object.Builtin{
+		Name:  "rest",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgType("rest", args[0], object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -77,13 +71,11 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": &object.Builtin{
+		Name:  "push",
+		Arity: 2,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgType("push", args[0], object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -95,6 +87,8 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"puts": &object.Builtin{
+		Name:     "puts",
+		Variadic: true,
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
 				fmt.Println(arg.Inspect())
@@ -103,3 +97,86 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 }
+
+// BuiltinNames returns the names of all builtin functions in a stable order,
+// so other backends can refer to them by index.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// DefineBuiltin binds builtin in env under its name, taking precedence over
+// the default builtin of the same name. A dotted name such as "math.abs"
+// defines abs in the namespace math, a module created on first use, so
+// programs call it as math.abs(x). Only namespaces bound in env itself are
+// extended, those of enclosing environments are shadowed by new ones.
+func DefineBuiltin(env *object.Environment, builtin *object.Builtin) error {
+	path := strings.Split(builtin.Name, ".")
+	for _, part := range path {
+		if part == "" {
+			return fmt.Errorf("invalid builtin name %q", builtin.Name)
+		}
+	}
+
+	var namespace *object.Module
+	lookup := func(name string) (object.Object, bool) {
+		if namespace == nil {
+			return env.GetLocal(name)
+		}
+		obj, ok := namespace.Exports[name]
+		return obj, ok
+	}
+	bind := func(name string, obj object.Object) {
+		if namespace == nil {
+			env.Set(name, obj)
+		} else {
+			namespace.Exports[name] = obj
+		}
+	}
+
+	for i, name := range path[:len(path)-1] {
+		obj, ok := lookup(name)
+		if !ok {
+			module := &object.Module{
+				Path:      strings.Join(path[:i+1], "."),
+				Exports:   make(map[string]object.Object),
+				Namespace: true,
+			}
+			bind(name, module)
+			namespace = module
+			continue
+		}
+
+		module, ok := obj.(*object.Module)
+		if !ok || !module.Namespace {
+			return fmt.Errorf("%s is not a namespace", strings.Join(path[:i+1], "."))
+		}
+		namespace = module
+	}
+
+	bind(path[len(path)-1], builtin)
+	return nil
+}
+
+// CheckArgType returns the error builtins report when arg, an argument of the
+// builtin called name, is not of one of types, or nil if it is.
+func CheckArgType(name string, arg object.Object, types ...object.ObjectType) *object.Error {
+	names := make([]string, len(types))
+	for i, t := range types {
+		if arg.Type() == t {
+			return nil
+		}
+		names[i] = string(t)
+	}
+
+	return newError("argument to `%s` must be %s, got %s", name, strings.Join(names, " or "), arg.Type())
+}
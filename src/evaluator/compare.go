@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"cmp"
+	"monkey/object"
+	"strings"
+)
+
+func isOrdering(operator string) bool {
+	switch operator {
+	case "<", ">", "<=", ">=":
+		return true
+	default:
+		return false
+	}
+}
+
+// evalOrdering applies an ordering operator using compareObjects.
+func evalOrdering(operator string, left, right object.Object) object.Object {
+	result, err := compareObjects(operator, left, right)
+	if err != nil {
+		return err
+	}
+
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(result < 0)
+	case ">":
+		return nativeBoolToBooleanObject(result > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(result <= 0)
+	default:
+		return nativeBoolToBooleanObject(result >= 0)
+	}
+}
+
+// compareObjects returns -1, 0 or +1 as left is less than, equal to or
+// greater than right. Numbers are ordered by value, strings lexicographically
+// and arrays element by element, an array that is a prefix of the other
+// coming first. Arrays containing themselves are handled the way object.Equal
+// does. operator is only used for error messages.
+func compareObjects(operator string, left, right object.Object) (int, *object.Error) {
+	return compare(operator, left, right, map[[2]object.Object]bool{})
+}
+
+func compare(operator string, left, right object.Object, visiting map[[2]object.Object]bool) (int, *object.Error) {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return compareNumbers(left, right), nil
+
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return strings.Compare(left.(*object.String).Value, right.(*object.String).Value), nil
+
+	case left.Type() == object.ARRAY_OBJ && right.Type() == object.ARRAY_OBJ:
+		leftElements := left.(*object.Array).Elements
+		rightElements := right.(*object.Array).Elements
+
+		// A pair already being compared further up is assumed equal, any
+		// difference is found by the comparison in progress
+		pair := [2]object.Object{left, right}
+		if visiting[pair] {
+			return 0, nil
+		}
+		visiting[pair] = true
+		defer delete(visiting, pair)
+
+		for i := 0; i < len(leftElements) && i < len(rightElements); i++ {
+			result, err := compare(operator, leftElements[i], rightElements[i], visiting)
+			if err != nil {
+				return 0, err
+			}
+			if result != 0 {
+				return result, nil
+			}
+		}
+		return cmp.Compare(len(leftElements), len(rightElements)), nil
+
+	case object.Equal(left, right):
+		// Values without an order, like booleans, can still be equal
+		return 0, nil
+
+	case left.Type() != right.Type():
+		return 0, newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+
+	default:
+		return 0, newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func compareNumbers(left, right object.Object) int {
+	leftInt, leftOk := left.(*object.Integer)
+	rightInt, rightOk := right.(*object.Integer)
+
+	switch {
+	case leftOk && rightOk:
+		return cmp.Compare(leftInt.Value, rightInt.Value)
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return toBigInt(left).Cmp(toBigInt(right))
+	default:
+		return cmp.Compare(toFloat(left), toFloat(right))
+	}
+}
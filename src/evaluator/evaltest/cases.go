@@ -0,0 +1,473 @@
+// Package evaltest holds programs and their expected results that the
+// tree-walking evaluator and the bytecode VM are both tested against, so the
+// two backends can't drift apart on the language they share.
+package evaltest
+
+// Case is a program and its expected result, one of:
+//   - int, float64, bool or string for integers, floats, booleans and strings
+//   - nil for null
+//   - Error for an error with that message
+//   - Inspect for any object with that Inspect output
+//   - NoValue for a program ending in a statement without a value
+type Case struct {
+	Input    string
+	Expected interface{}
+}
+
+type Error string
+
+type Inspect string
+
+type noValue struct{}
+
+var NoValue = noValue{}
+
+var IntegerExpressions = []Case{
+	{"5", 5},
+	{"10", 10},
+	{"-5", -5},
+	{"-10", -10},
+	{"5 + 5 + 5 + 5 - 10", 10},
+	{"2 * 2 * 2 * 2 * 2", 32},
+	{"-50 + 100 + -50", 0},
+	{"5 * 2 + 10", 20},
+	{"5 + 2 * 10", 25},
+	{"20 + 2 * -10", 0},
+	{"50 / 2 * 2 + 10", 60},
+	{"2 * (5 + 10)", 30},
+	{"3 * 3 * 3 + 10", 37},
+	{"3 * (3 * 3) + 10", 37},
+	{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+	{"7 / 2", 3},
+	{"-7 / 2", -4},
+	{"7 / -2", -4},
+	{"-7 / -2", 3},
+	{"-6 / 2", -3},
+	{"7 % 3", 1},
+	{"-7 % 3", 2},
+	{"7 % -3", -2},
+	{"-7 % -3", -1},
+	{"6 % 3", 0},
+	{"2 + 7 % 4 * 2", 8},
+	{"-99999999999999999999 % 7", 6},
+}
+
+var BigIntegerExpressions = []Case{
+	{"9223372036854775807 + 1", Inspect("9223372036854775808")},
+	{"-9223372036854775807 - 2", Inspect("-9223372036854775809")},
+	{"4294967296 * 4294967296", Inspect("18446744073709551616")},
+	{"-(-9223372036854775807 - 1)", Inspect("9223372036854775808")},
+	{"(-9223372036854775807 - 1) / -1", Inspect("9223372036854775808")},
+	{"123456789012345678901234567890", Inspect("123456789012345678901234567890")},
+	{"123456789012345678901234567890 / 10", Inspect("12345678901234567890123456789")},
+	{"-123456789012345678901234567890 * 2", Inspect("-246913578024691357802469135780")},
+	{"-99999999999999999999 / 7", Inspect("-14285714285714285715")},
+	// Results that fit in 64 bits again are plain integers
+	{"9223372036854775808 - 1", 9223372036854775807},
+	{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+	{"99999999999999999999 - 99999999999999999998", 1},
+	{"-9223372036854775808", -9223372036854775808},
+	{"9223372036854775808 > 9223372036854775807", true},
+	{"9223372036854775807 < 9223372036854775808", true},
+	{"-9223372036854775809 < -9223372036854775808", true},
+	{"9223372036854775808 == 9223372036854775807 + 1", true},
+	{"9223372036854775808 != 9223372036854775808", false},
+	{"9223372036854775808 == 9223372036854775808.0", true},
+	{"9223372036854775808 > 1.5", true},
+	{"9223372036854775807 * 2 > 9223372036854775807", true},
+	{"-99999999999999999999 < 0", true},
+	{`{9223372036854775808: 1}[9223372036854775807 + 1]`, 1},
+	{`{1: 1}[(9223372036854775807 + 1) - 9223372036854775807]`, 1},
+	{`[1][99999999999999999999]`, nil},
+}
+
+var FloatExpressions = []Case{
+	{"1.5", 1.5},
+	{"-1.5", -1.5},
+	{"-2.25", -2.25},
+	{"1e3", 1000.0},
+	{"2.5E-1", 0.25},
+	{"0.1 + 0.2", 0.30000000000000004},
+	{"1.5 + 1.5", 3.0},
+	{"1.5 * 2", 3.0},
+	{"2 * 0.25", 0.5},
+	{"3 - 0.5", 2.5},
+	{"7 / 2.0", 3.5},
+	{"7.5 % 2", 1.5},
+	{"-7.5 % 2", 0.5},
+	{"7.5 % -2", -0.5},
+	{"(1 + 2 + 3) / 4.0", 1.5},
+}
+
+var BooleanExpressions = []Case{
+	{"true", true},
+	{"false", false},
+	{"1 < 2", true},
+	{"1 > 2", false},
+	{"1 < 1", false},
+	{"1 > 1", false},
+	{"1 == 1", true},
+	{"1 != 1", false},
+	{"1 == 2", false},
+	{"1 != 2", true},
+	{"true == true", true},
+	{"false == false", true},
+	{"true == false", false},
+	{"true != false", true},
+	{"false != true", true},
+	{"(1 < 2) == true", true},
+	{"(1 < 2) == false", false},
+	{"(1 > 2) == true", false},
+	{"(1 > 2) == false", true},
+	{`"test" == "test"`, true},
+	{`"test" == " test"`, false},
+	{`"12345" == "12345"`, true},
+	{`"test" != "test"`, false},
+	{`"test" != " test"`, true},
+	{"1.5 < 2", true},
+	{"1 < 1.5", true},
+	{"2 > 1.5", true},
+	{"0.5 > 0.25", true},
+	{"1 == 1.0", true},
+	{"2.0 == 2", true},
+	{"1.0 != 1", false},
+	{"0.1 + 0.2 == 0.3", false},
+}
+
+var BangOperator = []Case{
+	{"!true", false},
+	{"!false", true},
+	{"!5", false},
+	{"!!true", true},
+	{"!!false", false},
+	{"!!5", true},
+	{"!(if (false) { 5; })", true},
+}
+
+var StringExpressions = []Case{
+	{`"Hello World!"`, "Hello World!"},
+	{`"monkey"`, "monkey"},
+	{`"mon" + "key"`, "monkey"},
+	{`"Hello" + " " + "World!"`, "Hello World!"},
+	{`"1" * 3`, "111"},
+	{`"abc" * 0`, ""},
+}
+
+var Comparisons = []Case{
+	{"1 <= 2", true},
+	{"2 <= 2", true},
+	{"3 <= 2", false},
+	{"1 >= 2", false},
+	{"2 >= 2", true},
+	{"2 >= 3", false},
+	{"1.5 <= 1", false},
+	{"2 >= 1.5", true},
+	{"99999999999999999999 >= 99999999999999999999", true},
+	{`"a" < "b"`, true},
+	{`"b" > "a"`, true},
+	{`"b" <= "a"`, false},
+	{`"ab" < "b"`, true},
+	{`"abc" <= "abc"`, true},
+	{`"abc" >= "abd"`, false},
+	{`"" < "a"`, true},
+	{"[1, 2] < [1, 3]", true},
+	{"[1, 2] > [1, 3]", false},
+	{"[1, 2] <= [1, 2]", true},
+	{"[1, 2] >= [1, 2, 0]", false},
+	{"[1] < [1, 0]", true},
+	{"[] < [1]", true},
+	{"[2] > [1, 5]", true},
+	{`[[1, "b"]] > [[1, "a"]]`, true},
+	{"[true, 1] < [true, 2]", true},
+	{"[1.5] < [2]", true},
+	{`"a" < 1`, Error("type mismatch: STRING < INTEGER")},
+	{`"a" <= 1`, Error("type mismatch: STRING <= INTEGER")},
+	{`[1] < ["a"]`, Error("type mismatch: INTEGER < STRING")},
+	{"[true] >= [false]", Error("unknown operator: BOOLEAN >= BOOLEAN")},
+	{"true < false", Error("unknown operator: BOOLEAN < BOOLEAN")},
+	{"[1] < 1", Error("type mismatch: ARRAY < INTEGER")},
+}
+
+var StructuralEquality = []Case{
+	{"[1, 2] == [1, 2]", true},
+	{"[1, [2]] == [1, [2]]", true},
+	{"[1, 2] != [1, 2]", false},
+	{"[1, 2] == [2, 1]", false},
+	{"[1, [2, [3]]] == [1, [2, [3]]]", true},
+	{"[1, [2, [3]]] == [1, [2, [4]]]", false},
+	{"[] == []", true},
+	{"[1] == [1.0]", true},
+	{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+	{`{"a": [1]} == {"a": [1]}`, true},
+	{`{"a": 1} == {"a": 2}`, false},
+	{`{"a": 1} != {"b": 1}`, true},
+	{"{} == {}", true},
+	{"[] == {}", false},
+	{`[true, "x"] == [true, "x"]`, true},
+	{"if (false) { 1 } == if (false) { 2 }", true},
+	{`1 == "1"`, false},
+	{`1 != "1"`, true},
+	{"let a = [1]; a == a", true},
+	{"fn(x) { x } == fn(x) { x }", false},
+	{"let f = fn(x) { x }; f == f", true},
+}
+
+var LogicalOperators = []Case{
+	{"true && true", true},
+	{"true && false", false},
+	{"false || true", true},
+	{"false || false", false},
+	{"1 && 2", 2},
+	{"1 || 2", 1},
+	{"if (false) { 1 } && 2", nil},
+	{"if (false) { 1 } || 2", 2},
+	{"0 || 2", 0},
+	{"1 < 2 && 2 < 3", true},
+	{"false || 1 < 2 && 2 > 3", false},
+	// The right operand is not evaluated when the left one decides
+	{"false && missing", false},
+	{"true || missing", true},
+	{"true && missing", Error("identifier not found: missing")},
+	{"let x = 5; x > 1 && x < 10", true},
+	{"let f = fn(x) { x > 1 && x < 10 }; f(0) || f(5)", true},
+}
+
+var IfElseExpressions = []Case{
+	{"if (true) { 10 }", 10},
+	{"if (false) { 10 }", nil},
+	{"if (1) { 10 }", 10},
+	{"if (1 < 2) { 10 }", 10},
+	{"if (1 > 2) { 10 }", nil},
+	{"if (1 > 2) { 10 } else { 20 }", 20},
+	{"if (1 < 2) { 10 } else { 20 }", 10},
+	{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+}
+
+var ReturnStatements = []Case{
+	{"return 10;", 10},
+	{"return 10; 9", 10},
+	{"return 2 * 5; 9", 10},
+	{"9; return 2 * 5; 9;", 10},
+	{`
+if (10 > 1) {
+  if (10 > 1) {
+    return 10;
+  }
+
+  return 1;
+}
+`, 10},
+}
+
+var LetStatements = []Case{
+	{"let a = 5; a;", 5},
+	{"let a = 5 * 5; a;", 25},
+	{"let a = 5; let b = a; b;", 5},
+	{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	{"let a = 5; let a = a + 1; a;", 6},
+	{"let x = 2;", NoValue},
+	{"1; let x = 2;", NoValue},
+	// The value of a `let` still sees the binding it shadows
+	{"let x = 1; let f = fn() { let x = 2; x }; f() + x", 3},
+	{"let x = 1; let f = fn() { let x = x + 1; x }; f()", 2},
+	{"let x = 1; let f = fn() { let x = x; x }; f()", 1},
+	{"let f = fn(x) { let g = fn() { let x = x * 2; x }; g() + x }; f(3)", 9},
+	// Locals from earlier calls don't leak into later ones
+	{"let f = fn() { let a = 10; a }; let g = fn() { let b = b; b }; f(); g()",
+		Error("identifier not found: b")},
+	{"let f = fn() { let a = 10; a }; let g = fn() { if (false) { let b = 1 }; b }; f(); g()",
+		Error("identifier not found: b")},
+}
+
+var ErrorHandling = []Case{
+	{"5 + true;", Error("type mismatch: INTEGER + BOOLEAN")},
+	{"5 + true; 5;", Error("type mismatch: INTEGER + BOOLEAN")},
+	{"-true", Error("unknown operator: -BOOLEAN")},
+	{"1.5 + true", Error("type mismatch: FLOAT + BOOLEAN")},
+	{"5 / 0", Error("division by zero")},
+	{"5 % 0", Error("modulo by zero")},
+	{"99999999999999999999 / 0", Error("division by zero")},
+	{"1.5 / 0.0", Error("division by zero")},
+	{"let f = fn(x) { 10 / x }; f(0)", Error("division by zero")},
+	{"99999999999999999999 + true", Error("type mismatch: INTEGER + BOOLEAN")},
+	{`"a" * 99999999999999999999`, Error("repetition count too large: STRING * 99999999999999999999")},
+	{"true + false;", Error("unknown operator: BOOLEAN + BOOLEAN")},
+	{`"Hello" - "World"`, Error("unknown operator: STRING - STRING")},
+	{"5; true + false; 5", Error("unknown operator: BOOLEAN + BOOLEAN")},
+	{"if (10 > 1) { true + false; }", Error("unknown operator: BOOLEAN + BOOLEAN")},
+	{`
+if (10 > 1) {
+  if (10 > 1) {
+    return true + false;
+  }
+
+  return 1;
+}
+`, Error("unknown operator: BOOLEAN + BOOLEAN")},
+	{"foobar", Error("identifier not found: foobar")},
+	{`"1" * -3`, Error("negative argument error: STRING * -3")},
+	{`{"name": "Monkey"}[fn(x) { x }];`, Error("unusable as hash key: FUNCTION")},
+	{`{[1, fn(x) { x }]: 1}`, Error("unusable as hash key: ARRAY")},
+	{`{"a": 1}[[len]]`, Error("unusable as hash key: ARRAY")},
+	{"let f = fn() { 1 + true }; f() + 1", Error("type mismatch: INTEGER + BOOLEAN")},
+	{"1(2)", Error("not a function: INTEGER")},
+	{"let f = fn(a, b) { a }; f(1)", Error("wrong number of arguments. got=1, want=2")},
+}
+
+var FunctionApplication = []Case{
+	{"let identity = fn(x) { x; }; identity(5);", 5},
+	{"let identity = fn(x) { return x; }; identity(5);", 5},
+	{"let double = fn(x) { x * 2; }; double(5);", 10},
+	{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+	{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+	{"fn(x) { x; }(5)", 5},
+	{"let noReturn = fn() { }; noReturn();", nil},
+	{"let early = fn() { return 99; 100; }; early();", 99},
+	{`
+let globalSeed = 50;
+let minusOne = fn() { let num = 1; globalSeed - num; }
+let minusTwo = fn() { let num = 2; globalSeed - num; }
+minusOne() + minusTwo();
+`, 97},
+}
+
+var Closures = []Case{
+	{`
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);
+`, 4},
+	{`
+let newAdderOuter = fn(a, b) {
+  let c = a + b;
+  fn(d) {
+    let e = d + c;
+    fn(f) { e + f; };
+  };
+};
+let newAdderInner = newAdderOuter(1, 2)
+let adder = newAdderInner(3);
+adder(8);
+`, 14},
+}
+
+var RecursiveFunctions = []Case{
+	{`
+let fibonacci = fn(x) {
+  if (x == 0) {
+    return 0;
+  } else {
+    if (x == 1) {
+      return 1;
+    } else {
+      fibonacci(x - 1) + fibonacci(x - 2);
+    }
+  }
+};
+fibonacci(15);
+`, 610},
+	{`
+let wrapper = fn() {
+  let countDown = fn(x) {
+    if (x == 0) { return 0; } else { countDown(x - 1); }
+  };
+  countDown(1);
+};
+wrapper();
+`, 0},
+	{`
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+isEven(10);
+`, true},
+	{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5000)", 5000},
+	// Runaway recursion fails instead of exhausting the stack
+	{"let f = fn(n) { 1 + f(n + 1) }; f(0)", Error("maximum recursion depth exceeded")},
+}
+
+// TailCalls recurse far deeper than calls in any other position could.
+var TailCalls = []Case{
+	{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(2000)", 0},
+	{"let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } }; countdown(1000000)", 0},
+	{"let countdown = fn(n) { if (n > 0) { return countdown(n - 1) }; 42 }; countdown(1000000)", 42},
+	{"let sum = fn(n, acc) { if (n == 0) { return acc }; sum(n - 1, acc + n) }; sum(100000, 0)", 5000050000},
+	{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+if (even(100001)) { 1 } else { 0 }`, 0},
+	// Calls to builtins in tail position run directly
+	{"let f = fn(a) { len(a) }; f([1, 2])", 2},
+}
+
+var ArrayExpressions = []Case{
+	{"[]", Inspect("[]")},
+	{"[1, 2, 3]", Inspect("[1, 2, 3]")},
+	{"[1, 2 * 2, 3 + 3]", Inspect("[1, 4, 6]")},
+	{"[1 + 2, 3 * 4, 5 + 6]", Inspect("[3, 12, 11]")},
+	{"[1, 2, 3][0]", 1},
+	{"[1, 2, 3][1]", 2},
+	{"[1, 2, 3][2]", 3},
+	{"let i = 0; [1][i];", 1},
+	{"[1, 2, 3][1 + 1];", 3},
+	{"[[1, 1, 1]][0][0]", 1},
+	{"let myArray = [1, 2, 3]; myArray[2];", 3},
+	{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+	{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+	{"[1, 2, 3][3]", nil},
+	{"[1, 2, 3][-1]", nil},
+}
+
+var HashExpressions = []Case{
+	{"{}", Inspect("{}")},
+	{"{1: 2, 2: 3}", Inspect("{1: 2, 2: 3}")},
+	{`let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`, Inspect("{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}")},
+	{`{"b": 1, "a": 2, 3: 4}`, Inspect("{b: 1, a: 2, 3: 4}")},
+	{`{"a": 1, "b": 2, "a": 3}`, Inspect("{a: 3, b: 2}")},
+	{`{"foo": 5}["foo"]`, 5},
+	{`{"foo": 5}["bar"]`, nil},
+	{`let key = "foo"; {"foo": 5}[key]`, 5},
+	{`{}["foo"]`, nil},
+	{`{5: 5}[5]`, 5},
+	{`{true: 5}[true]`, 5},
+	{`{false: 5}[false]`, 5},
+	{`{"foo": 5}.foo`, 5},
+	{`{[1, 2]: 5}[[1, 2]]`, 5},
+	{`{[1, 2]: 5}[[2, 1]]`, nil},
+	{`let x = 1; let y = 2; {[x, y]: 5}[[1, 1 + 1]]`, 5},
+	{`{[[1], "a"]: 5}[[[1], "a"]]`, 5},
+	{`{{"a": 1, "b": 2}: 5}[{"b": 2, "a": 1}]`, 5},
+	{`{{"a": [1]}: 5}[{"a": [1]}]`, 5},
+	{`{1: 5}[1.0]`, 5},
+	{`{1e19: 5}[10000000000000000000]`, 5},
+	{`{"foo": {"bar": 5}}.foo.bar`, 5},
+}
+
+var BuiltinFunctions = []Case{
+	{`len("")`, 0},
+	{`len("four")`, 4},
+	{`len("hello world")`, 11},
+	{`len([1, 2, 3])`, 3},
+	{`len(1)`, Error("argument to `len` not supported, got INTEGER")},
+	{`len("one", "two")`, Error("wrong number of arguments. got=2, want=1")},
+	{`first([1, 2, 3])`, 1},
+	{`first(1)`, Error("argument to `first` must be ARRAY, got INTEGER")},
+	{`last([1, 2])`, 2},
+	{`last([1, 2, 3])`, 3},
+	{`last(1)`, Error("argument to `last` must be ARRAY, got INTEGER")},
+	{`rest([1, 2, 3])`, Inspect("[2, 3]")},
+	{`rest(1)`, Error("argument to `rest` must be ARRAY, got INTEGER")},
+	{`push([], 1)`, Inspect("[1]")},
+	{`push(1, 2)`, Error("argument to `push` must be ARRAY, got INTEGER")},
+	{`push([])`, Error("wrong number of arguments. got=1, want=2")},
+	{`let len = fn(x) { 42 }; len("abc")`, 42},
+}
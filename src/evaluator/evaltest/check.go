@@ -0,0 +1,60 @@
+package evaltest
+
+import (
+	"monkey/object"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Check asserts that actual is the result tc expects.
+func Check(assert *assert.Assertions, tc Case, actual object.Object) {
+	input := tc.Input
+
+	switch expected := tc.Expected.(type) {
+	case int:
+		result, ok := actual.(*object.Integer)
+		if assert.True(ok, "%q: object is not Integer. got=%T (%+v)", input, actual, actual) {
+			assert.Equal(int64(expected), result.Value, input)
+		}
+
+	case float64:
+		result, ok := actual.(*object.Float)
+		if assert.True(ok, "%q: object is not Float. got=%T (%+v)", input, actual, actual) {
+			assert.Equal(expected, result.Value, input)
+		}
+
+	case bool:
+		result, ok := actual.(*object.Boolean)
+		if assert.True(ok, "%q: object is not Boolean. got=%T (%+v)", input, actual, actual) {
+			assert.Equal(expected, result.Value, input)
+		}
+
+	case string:
+		result, ok := actual.(*object.String)
+		if assert.True(ok, "%q: object is not String. got=%T (%+v)", input, actual, actual) {
+			assert.Equal(expected, result.Value, input)
+		}
+
+	case nil:
+		if assert.NotNil(actual, "%q: no result", input) {
+			assert.Equal(object.ObjectType(object.NULL_OBJ), actual.Type(), "%q: got %s", input, actual.Inspect())
+		}
+
+	case Error:
+		result, ok := actual.(*object.Error)
+		if assert.True(ok, "%q: object is not Error. got=%T (%+v)", input, actual, actual) {
+			assert.Equal(string(expected), result.Message, input)
+		}
+
+	case Inspect:
+		if assert.NotNil(actual, "%q: no result", input) {
+			assert.Equal(string(expected), actual.Inspect(), input)
+		}
+
+	case noValue:
+		assert.Nil(actual, input)
+
+	default:
+		assert.Fail("unsupported expected value", "%q: %T", input, expected)
+	}
+}
@@ -2,18 +2,40 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/token"
 	"strings"
 )
 
+// DefaultMaxCallDepth is how many function calls can be active at once,
+// unless the environment sets another limit. Deeper recursion fails with an
+// error instead of overflowing the Go stack. Tail calls don't add to the depth.
+const DefaultMaxCallDepth = 10000
+
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// Errors take the position of the innermost node they surface from
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -33,11 +55,30 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 
-	case *ast.LetStatement:
+	case *ast.ThrowStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		return newThrownError(val)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
+	case *ast.LetStatement:
+		val := Eval(node.Value, env)
+		if interrupts(val) {
+			return val
+		}
 		env.Set(node.Name.Value, val)
 
 	// Expressions
@@ -54,6 +95,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return left
 		}
 
+		// The right operand is only evaluated if it decides the result
+		switch {
+		case node.Operator == "&&" && !isTruthy(left):
+			return left
+		case node.Operator == "||" && isTruthy(left):
+			return left
+		case node.Operator == "&&" || node.Operator == "||":
+			return Eval(node.Right, env)
+		}
+
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -62,9 +113,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{
+			Parameters: node.Parameters,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Body:       node.Body,
+			Env:        env,
+			Name:       node.Name,
+		}
 
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
@@ -75,7 +131,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		if fn, ok := function.(*object.Function); ok && node.Tail {
+			return &object.TailCall{Function: fn, Args: args, CallSite: node.Function.Pos()}
+		}
+		return applyFunction(function, args, node.Function.Pos(), env.CallDepth())
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -98,15 +157,37 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalIndexExpression(left, index)
 
+	case *ast.MemberExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return evalIndexExpression(left, &object.String{Value: node.Member.Value})
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ImportExpression:
+		return evalImportExpression(node, env)
+
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return object.IntegerFromBig(node.Big)
+		}
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -140,21 +221,42 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	for _, stmt := range block.Statements {
 		result = Eval(stmt, env)
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
-				return result
-			}
+		if interrupts(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
+// interrupts reports whether obj stops the evaluation of the block it comes
+// from: an error, a return or a loop signal.
+func interrupts(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	switch obj.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			evaluated := evalSpread(spread, object.ARRAY_OBJ, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			result = append(result, evaluated.(*object.Array).Elements...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -165,29 +267,135 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// evalSpread evaluates the value of a spread, which must be of type want.
+func evalSpread(node *ast.SpreadExpression, want object.ObjectType, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	if value.Type() != want {
+		err := newError("cannot spread %s, want %s", value.Type(), want)
+		err.Pos = node.Pos()
+		return err
+	}
+
+	return value
+}
+
+// applyFunction calls fn with args. callSite is where the call happens and is
+// recorded in the stack trace of any error the call returns, callDepth is the
+// number of calls already active there.
+func applyFunction(fn object.Object, args []object.Object, callSite token.Position, callDepth int) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendedFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		// Need to unwrap to avoid returning from outer code blocks
-		// We only want to return from the function scope
-		return unwrapReturnValue(evaluated)
+		return callFunction(fn, args, callSite, callDepth+1)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Call(args...)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendedFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosingEnvironment(fn.Env)
+// callFunction runs fn, then the tail calls it ends with, in a loop. A stack
+// trace keeps the last call of such a chain above the call that started it.
+func callFunction(fn *object.Function, args []object.Object, callSite token.Position, callDepth int) object.Object {
+	frames := []object.StackFrame{{Function: fn.Name, Pos: callSite}}
+
+	if callDepth > maxCallDepth(fn.Env) {
+		err := newError("maximum recursion depth exceeded")
+		err.Stack = frames
+		return err
+	}
+
+	for {
+		extendedEnv, evaluated := extendedFunctionEnv(fn, args, callDepth)
+		if extendedEnv != nil {
+			// Need to unwrap to avoid returning from outer code blocks
+			// We only want to return from the function scope
+			evaluated = unwrapReturnValue(Eval(fn.Body, extendedEnv))
+		}
+
+		switch result := evaluated.(type) {
+		case nil:
+			// Empty bodies and bodies ending in `let` produce no value
+			return NULL
+		case *object.Error:
+			result.Stack = append(result.Stack, frames...)
+			return result
+		case *object.TailCall:
+			fn, args = result.Function, result.Args
+			tailFrame := object.StackFrame{Function: fn.Name, Pos: result.CallSite}
+			frames = []object.StackFrame{tailFrame, frames[len(frames)-1]}
+		default:
+			return result
+		}
+	}
+}
+
+func maxCallDepth(env *object.Environment) int {
+	if depth := env.MaxCallDepth(); depth > 0 {
+		return depth
+	}
+	return DefaultMaxCallDepth
+}
+
+// extendedFunctionEnv binds the parameters of fn to args, or returns an error
+// if they don't match. Defaults are evaluated in order in the new environment,
+// so they can use the parameters before them.
+func extendedFunctionEnv(fn *object.Function, args []object.Object, callDepth int) (*object.Environment, object.Object) {
+	if err := checkArity(fn, len(args)); err != nil {
+		return nil, err
+	}
+
+	env := object.NewCallEnvironment(fn.Env, callDepth)
 
 	for paramI, param := range fn.Parameters {
-		env.Set(param.Value, args[paramI])
+		if paramI < len(args) {
+			env.Set(param.Value, args[paramI])
+			continue
+		}
+
+		value := Eval(fn.Defaults[paramI], env)
+		if isError(value) {
+			return nil, value
+		}
+		env.Set(param.Value, value)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+// checkArity returns an error if fn can't take n arguments.
+func checkArity(fn *object.Function, n int) *object.Error {
+	required := len(fn.Parameters)
+	for i, def := range fn.Defaults {
+		if def != nil {
+			required = i
+			break
+		}
 	}
 
-	return env
+	if n >= required && (n <= len(fn.Parameters) || fn.Rest != nil) {
+		return nil
+	}
+
+	switch {
+	case required == len(fn.Parameters) && fn.Rest == nil:
+		return newError("wrong number of arguments. got=%d, want=%d", n, required)
+	case n < required:
+		return newError("wrong number of arguments. got=%d, want at least %d", n, required)
+	default:
+		return newError("wrong number of arguments. got=%d, want at most %d", n, len(fn.Parameters))
+	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -201,9 +409,16 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		return evalArrayIndexExpression(left.(*object.Array), index.(*object.Integer).Value)
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			// A BigInteger, out of the bounds of any array
+			return NULL
+		}
+		return evalArrayIndexExpression(left.(*object.Array), integer.Value)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left.(*object.Hash), index)
+	case left.Type() == object.MODULE_OBJ:
+		return evalModuleIndexExpression(left.(*object.Module), index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -218,17 +433,16 @@ func evalArrayIndexExpression(array *object.Array, index int64) object.Object {
 }
 
 func evalHashIndexExpression(hash *object.Hash, index object.Object) object.Object {
-	key, ok := index.(object.HashTable)
-	if !ok {
+	if !object.Hashable(index) {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hash.Pairs[key.HashKey()]
+	value, ok := hash.Get(index)
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
 }
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
@@ -256,26 +470,48 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return object.IntegerFromBig(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInteger:
+		return object.IntegerFromBig(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	switch {
+	case (operator == "/" || operator == "%") && isNumber(left) && isNumber(right) && isZero(right):
+		if operator == "%" {
+			return newError("modulo by zero")
+		}
+		return newError("division by zero")
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left.(*object.Integer), right.(*object.Integer))
+		leftInt, leftOk := left.(*object.Integer)
+		rightInt, rightOk := right.(*object.Integer)
+		if leftOk && rightOk {
+			return evalIntegerInfixExpression(operator, leftInt, rightInt)
+		}
+		return evalBigIntegerInfixExpression(operator, toBigInt(left), toBigInt(right))
+	case isNumber(left) && isNumber(right):
+		// At least one of them is a float, the other is promoted
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left.(*object.String), right.(*object.String))
-	case left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalStringMultiplication(left.(*object.String), right.(*object.Integer))
+	case left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ && operator == "*":
+		return evalStringMultiplication(left.(*object.String), right)
+	case left.Type() == object.ARRAY_OBJ && right.Type() == object.ARRAY_OBJ && isOrdering(operator):
+		return evalOrdering(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -284,45 +520,72 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{}
+
+	for _, pair := range node.Pairs {
+		if spread, ok := pair.Key.(*ast.SpreadExpression); ok {
+			spreadHash := evalSpread(spread, object.HASH_OBJ, env)
+			if isError(spreadHash) {
+				return spreadHash
+			}
+			for _, spreadPair := range spreadHash.(*object.Hash).Pairs() {
+				hash.Set(spreadPair.Key, spreadPair.Value)
+			}
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.HashTable)
-		if !ok {
+		if !object.Hashable(key) {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(key, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalIntegerInfixExpression(operator string, left *object.Integer, right *object.Integer) object.Object {
+	var result int64
+	overflow := false
+
 	switch operator {
 	case "+":
-		return &object.Integer{Value: left.Value + right.Value}
+		result, overflow = addInt64(left.Value, right.Value)
 	case "-":
-		return &object.Integer{Value: left.Value - right.Value}
+		result, overflow = subInt64(left.Value, right.Value)
 	case "*":
-		return &object.Integer{Value: left.Value * right.Value}
+		result, overflow = mulInt64(left.Value, right.Value)
 	case "/":
-		return &object.Integer{Value: left.Value / right.Value}
+		result, overflow = floorDivInt64(left.Value, right.Value)
+	case "%":
+		result = floorModInt64(left.Value, right.Value)
+	}
+
+	if overflow {
+		return evalBigIntegerInfixExpression(operator, big.NewInt(left.Value), big.NewInt(right.Value))
+	}
+
+	switch operator {
+	case "+", "-", "*", "/", "%":
+		return &object.Integer{Value: result}
 	case "<":
 		return nativeBoolToBooleanObject(left.Value < right.Value)
 	case ">":
 		return nativeBoolToBooleanObject(left.Value > right.Value)
+	case "<=":
+		return nativeBoolToBooleanObject(left.Value <= right.Value)
+	case ">=":
+		return nativeBoolToBooleanObject(left.Value >= right.Value)
 	case "==":
 		return nativeBoolToBooleanObject(left.Value == right.Value)
 	case "!=":
@@ -332,10 +595,81 @@ func evalIntegerInfixExpression(operator string, left *object.Integer, right *ob
 	}
 }
 
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: floorModFloat64(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func isZero(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value == 0
+	case *object.BigInteger:
+		return obj.Value.Sign() == 0
+	case *object.Float:
+		return obj.Value == 0
+	default:
+		return false
+	}
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
 func evalStringInfixExpression(operator string, left *object.String, right *object.String) object.Object {
 	switch operator {
 	case "+":
 		return &object.String{Value: left.Value + right.Value}
+	case "<":
+		return nativeBoolToBooleanObject(left.Value < right.Value)
+	case ">":
+		return nativeBoolToBooleanObject(left.Value > right.Value)
+	case "<=":
+		return nativeBoolToBooleanObject(left.Value <= right.Value)
+	case ">=":
+		return nativeBoolToBooleanObject(left.Value >= right.Value)
 	case "==":
 		return nativeBoolToBooleanObject(left.Value == right.Value)
 	case "!=":
@@ -345,7 +679,15 @@ func evalStringInfixExpression(operator string, left *object.String, right *obje
 	}
 }
 
-func evalStringMultiplication(str *object.String, int *object.Integer) object.Object {
+func evalStringMultiplication(str *object.String, count object.Object) object.Object {
+	int, ok := count.(*object.Integer)
+	if !ok {
+		if count.(*object.BigInteger).Value.Sign() < 0 {
+			return newError("negative argument error: %s * %s", str.Type(), count.Inspect())
+		}
+		return newError("repetition count too large: %s * %s", str.Type(), count.Inspect())
+	}
+
 	if int.Value < 0 {
 		return newError("negative argument error: %s * %d", str.Type(), int.Value)
 	}
@@ -373,6 +715,62 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnclosingEnvironment(env)
+		catchEnv.Set(te.CatchParam.Value, caughtError(err))
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		// The finally block only changes the outcome when it fails, returns or
+		// leaves a loop
+		finally := Eval(te.Finally, env)
+		if interrupts(finally) {
+			return finally
+		}
+	}
+
+	return result
+}
+
+// newThrownError wraps a thrown value. Strings become the message as is and
+// hashes with a "message" key, such as a caught error, keep their message.
+func newThrownError(val object.Object) *object.Error {
+	message := val.Inspect()
+
+	if hash, ok := val.(*object.Hash); ok {
+		if msg, ok := hash.Get(&object.String{Value: "message"}); ok {
+			message = msg.Inspect()
+		}
+	}
+
+	return &object.Error{Message: message, Value: val}
+}
+
+// caughtError turns an error into the hash bound by `catch`, with its message,
+// stack trace and the thrown value.
+func caughtError(err *object.Error) *object.Hash {
+	trace := []object.Object{}
+	for _, frame := range err.Stack {
+		trace = append(trace, &object.String{Value: frame.String()})
+	}
+
+	var value object.Object = &object.String{Value: err.Message}
+	if err.Value != nil {
+		value = err.Value
+	}
+
+	hash := &object.Hash{}
+	hash.Set(&object.String{Value: "message"}, &object.String{Value: err.Message})
+	hash.Set(&object.String{Value: "trace"}, &object.Array{Elements: trace})
+	hash.Set(&object.String{Value: "value"}, value)
+
+	return hash
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -385,6 +783,34 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: %s", node.Value)
 }
 
+// EvalInfix, EvalPrefix and EvalIndex apply an operator to already evaluated
+// operands. They are exported so the bytecode VM shares the exact semantics of
+// the tree-walking evaluator.
+func EvalInfix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+func EvalPrefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+func EvalIndex(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// ApplyFunction calls a function or builtin from outside of Monkey code.
+func ApplyFunction(fn object.Object, args []object.Object) object.Object {
+	return applyFunction(fn, args, token.Position{}, 0)
+}
+
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+func NativeBoolToBooleanObject(input bool) *object.Boolean {
+	return nativeBoolToBooleanObject(input)
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
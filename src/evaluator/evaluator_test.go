@@ -1,484 +1,694 @@
 package evaluator
 
 import (
+	"monkey/evaluator/evaltest"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/token"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
+	runEvalTests(t, evaltest.IntegerExpressions)
+}
+
+func TestEvalBigIntegerExpression(t *testing.T) {
+	runEvalTests(t, evaltest.BigIntegerExpressions)
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	runEvalTests(t, evaltest.FloatExpressions)
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	runEvalTests(t, evaltest.BooleanExpressions)
+}
+
+func TestStringExpressions(t *testing.T) {
+	runEvalTests(t, evaltest.StringExpressions)
+}
+
+func TestComparisons(t *testing.T) {
+	runEvalTests(t, evaltest.Comparisons)
+}
+
+func TestStructuralEquality(t *testing.T) {
+	runEvalTests(t, evaltest.StructuralEquality)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	runEvalTests(t, evaltest.LogicalOperators)
+	runEvalTests(t, []evaltest.Case{
+		{Input: "let calls = fn() { throw \"called\" }; 1 || calls()", Expected: 1},
+	})
+}
+
+func TestBangOperator(t *testing.T) {
+	runEvalTests(t, evaltest.BangOperator)
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	runEvalTests(t, evaltest.IfElseExpressions)
+}
+
+func TestReturnStatements(t *testing.T) {
+	runEvalTests(t, evaltest.ReturnStatements)
+}
+
+func TestLetStatements(t *testing.T) {
+	runEvalTests(t, evaltest.LetStatements)
+}
+
+func TestErrorHandling(t *testing.T) {
+	runEvalTests(t, evaltest.ErrorHandling)
+	runEvalTests(t, []evaltest.Case{
+		{Input: `throw "custom failure"; 5`, Expected: evaltest.Error("custom failure")},
+		{Input: `try { throw "not caught" } finally { 1 }`, Expected: evaltest.Error("not caught")},
+		{Input: `try { 1 } finally { throw "from finally" }`, Expected: evaltest.Error("from finally")},
+		{Input: `try { throw "first" } catch (e) { throw "second" }`, Expected: evaltest.Error("second")},
+	})
+}
+
+func TestErrorPositions(t *testing.T) {
 	assert := assert.New(t)
 	tests := []struct {
-		input    string
-		expected int64
+		input       string
+		expectedPos token.Position
 	}{
-		{"5", 5},
-		{"10", 10},
-		{"-5", -5},
-		{"-10", -10},
-		{"5 + 5 + 5 + 5 - 10", 10},
-		{"2 * 2 * 2 * 2 * 2", 32},
-		{"-50 + 100 + -50", 0},
-		{"5 * 2 + 10", 20},
-		{"5 + 2 * 10", 25},
-		{"20 + 2 * -10", 0},
-		{"50 / 2 * 2 + 10", 60},
-		{"2 * (5 + 10)", 30},
-		{"3 * 3 * 3 + 10", 37},
-		{"3 * (3 * 3) + 10", 37},
-		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"5 + true;", token.Position{Line: 1, Column: 3}},
+		{"let a = 1;\nlet b = a + -true;", token.Position{Line: 2, Column: 13}},
+		{"let f = fn() {\n  foobar\n};\nf()", token.Position{Line: 2, Column: 3}},
+		{`len(1)`, token.Position{Line: 1, Column: 4}},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		testIntegerObject(assert, evaluated, tt.expected)
+
+		errorObj, ok := evaluated.(*object.Error)
+		if assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+			assert.Equal(tt.expectedPos, errorObj.Pos, tt.input)
+		}
 	}
 }
 
-func TestEvalBooleanExpression(t *testing.T) {
+func TestErrorStackTraces(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input         string
+		expectedStack []object.StackFrame
+	}{
+		{"5 + true", nil},
+		{
+			`let inner = fn(x) { x + true };
+let outer = fn(x) { inner(x) * 2 };
+outer(1);`,
+			[]object.StackFrame{
+				{Function: "inner", Pos: token.Position{Line: 2, Column: 21}},
+				{Function: "outer", Pos: token.Position{Line: 3, Column: 1}},
+			},
+		},
+		{
+			`let apply = fn(f) { f() };
+apply(fn() { len(1) })`,
+			[]object.StackFrame{
+				{Function: "", Pos: token.Position{Line: 1, Column: 21}},
+				{Function: "apply", Pos: token.Position{Line: 2, Column: 1}},
+			},
+		},
+		{
+			// Only the last of a chain of tail calls is kept
+			`let fail = fn(n) { if (n == 0) { len(1) } else { fail(n - 1) } };
+let f = fn() { fail(3) * 1 };
+f()`,
+			[]object.StackFrame{
+				{Function: "fail", Pos: token.Position{Line: 1, Column: 50}},
+				{Function: "fail", Pos: token.Position{Line: 2, Column: 16}},
+				{Function: "f", Pos: token.Position{Line: 3, Column: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObj, ok := evaluated.(*object.Error)
+		if assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+			assert.Equal(tt.expectedStack, errorObj.Stack, tt.input)
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
 	assert := assert.New(t)
 	tests := []struct {
 		input    string
-		expected bool
+		expected interface{}
 	}{
-		{"true", true},
-		{"false", false},
-		{"1 < 2", true},
-		{"1 > 2", false},
-		{"1 < 1", false},
-		{"1 > 1", false},
-		{"1 == 1", true},
-		{"1 != 1", false},
-		{"1 == 2", false},
-		{"1 != 2", true},
-		{"true == true", true},
-		{"false == false", true},
-		{"true == false", false},
-		{"true != false", true},
-		{"false != true", true},
-		{"(1 < 2) == true", true},
-		{"(1 < 2) == false", false},
-		{"(1 > 2) == true", false},
-		{"(1 > 2) == false", true},
-		{`"test" == "test"`, true},
-		{`"test" == " test"`, false},
-		{`"12345" == "12345"`, true},
-		{`"test" != "test"`, false},
-		{`"test" != " test"`, true},
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "boom"; 1 } catch (e) { e["message"] }`, "boom"},
+		{`try { {"a": 1}["a"] + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { len(1) } catch (e) { 5 }`, 5},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { throw 42 } catch (e) { e["message"] }`, "42"},
+		{`try { throw {"message": "custom"} } catch (e) { e["message"] }`, "custom"},
+		{`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e["message"] }`, "inner"},
+		{`let e = 1; try { throw "x" } catch (e) { 2 }; e`, 1},
+		{`try { throw "a" } catch (e) { 1 } finally { 2 }`, 1},
+		{`try { 3 } finally { 4 }`, 3},
+		{`let f = fn() { try { return 1 } catch (e) { 2 }; 3 }; f()`, 1},
+		{`let f = fn() { try { 1 } finally { return 2 } }; f()`, 2},
+		{
+			`let fail = fn() { throw "deep" };
+let wrap = fn() { fail() };
+try { wrap() } catch (e) { e["trace"] }`,
+			[]string{"at fail (2:19)", "at wrap (3:7)"},
+		},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		testBooleanObject(assert, evaluated, tt.expected)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if assert.True(ok, "object is not String. got=%T (%+v)", evaluated, evaluated) {
+				assert.Equal(expected, str.Value)
+			}
+		case []string:
+			arr, ok := evaluated.(*object.Array)
+			if assert.True(ok, "object is not Array. got=%T (%+v)", evaluated, evaluated) {
+				assert.Equal(len(expected), len(arr.Elements))
+				for i, el := range arr.Elements {
+					assert.Equal(expected[i], el.Inspect())
+				}
+			}
+		}
 	}
 }
 
-func TestStringLiteral(t *testing.T) {
+func TestFunctionObject(t *testing.T) {
 	assert := assert.New(t)
-	input := `"Hello World!"`
+	input := "fn(x) { x + 2; };"
 
 	evaluated := testEval(input)
-	str, ok := evaluated.(*object.String)
-	assert.True(ok, "object is not String. got=%T (%+v)", evaluated, evaluated)
-	assert.Equal("Hello World!", str.Value)
+	fn, ok := evaluated.(*object.Function)
+	assert.True(ok, "evaluated was not a function, got %T(%+v)", evaluated, evaluated)
+
+	assert.Equal(1, len(fn.Parameters))
+	assert.Equal("x", fn.Parameters[0].String())
+	assert.Equal("(x + 2)", fn.Body.String())
 }
 
-func TestStringConcatenation(t *testing.T) {
-	assert := assert.New(t)
+func TestFunctionApplication(t *testing.T) {
+	runEvalTests(t, evaltest.FunctionApplication)
+}
 
+func TestFunctionParameters(t *testing.T) {
+	assert := assert.New(t)
 	tests := []struct {
 		input    string
-		expected string
+		expected interface{}
 	}{
-		{`"Hello" + " " + "World!"`, "Hello World!"},
-		{`"1" * 3`, "111"},
-		{`"abc" * 0"`, ""},
+		{"let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"let f = fn(a, b = a * 2) { a + b }; f(3)", 9},
+		{"let n = 0; let f = fn(a = n += 1) { a }; f(); f(); f(5); n", 2},
+		{"let f = fn(...rest) { rest }; f()", "[]"},
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", "[2, 3]"},
+		{"let f = fn(a, b = 2, ...rest) { [a, b, rest] }; f(1)", "[1, 2, []]"},
+		{"let f = fn(a, b = 2, ...rest) { [a, b, rest] }; f(1, 3, 4, 5)", "[1, 3, [4, 5]]"},
+		{"fn(a, b = 2) {}", "fn(a, b = 2) {\n\n}"},
+		{"let f = fn(a, b) { a }; f(1)", "wrong number of arguments. got=1, want=2"},
+		{"let f = fn(a) { a }; f(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(a, b = 1) { a }; f()", "wrong number of arguments. got=0, want at least 1"},
+		{"let f = fn(a, b = 1) { a }; f(1, 2, 3)", "wrong number of arguments. got=3, want at most 2"},
+		{"let f = fn(a, ...rest) { a }; f()", "wrong number of arguments. got=0, want at least 1"},
+		{"let f = fn(a = 1 + true) { a }; f()", "type mismatch: INTEGER + BOOLEAN"},
+		// Arity errors of tail calls too
+		{"let g = fn(a) { a }; let f = fn() { g() }; f()", "wrong number of arguments. got=0, want=1"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		str, ok := evaluated.(*object.String)
-		assert.True(ok, "object is not String. got=%T (%+v)", evaluated, evaluated)
-		assert.Equal(tt.expected, str.Value)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				assert.Equal(expected, errObj.Message, tt.input)
+			} else {
+				assert.Equal(expected, evaluated.Inspect(), tt.input)
+			}
+		}
+	}
+
+	// The error is reported at the call, with the function in the trace
+	evaluated := testEval("let f = fn(a, b) { a };\nf(1)")
+	errObj, ok := evaluated.(*object.Error)
+	if assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+		assert.Equal(token.Position{Line: 2, Column: 2}, errObj.Pos)
+		assert.Equal([]object.StackFrame{{Function: "f", Pos: token.Position{Line: 2, Column: 1}}}, errObj.Stack)
 	}
 }
 
-func TestBangOperator(t *testing.T) {
+func TestSpreadExpressions(t *testing.T) {
 	assert := assert.New(t)
 	tests := []struct {
 		input    string
-		expected bool
+		expected interface{}
 	}{
-		{"!true", false},
-		{"!false", true},
-		{"!5", false},
-		{"!!true", true},
-		{"!!false", false},
-		{"!!5", true},
+		{"let add = fn(a, b, c) { a + b + c }; add(...[1, 2, 3])", 6},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2], ...[], 3)", 6},
+		{"let f = fn(...rest) { rest }; f(...[1, 2], 3)", "[1, 2, 3]"},
+		{"len(...[[1, 2]])", 2},
+		{"let xs = [2, 3]; [1, ...xs, 4, ...xs]", "[1, 2, 3, 4, 2, 3]"},
+		{"let xs = [1]; let ys = [...xs]; ys[0] = 2; xs", "[1]"},
+		{"[...[]]", "[]"},
+		{`let a = {"x": 1, "y": 2}; {...a, "y": 3, "z": 4}`, "{x: 1, y: 3, z: 4}"},
+		{`let a = {"x": 1}; {"x": 0, "y": 2, ...a}`, "{x: 1, y: 2}"},
+		{`{...{}}`, "{}"},
+		{"f(...1)", "identifier not found: f"},
+		{"[...1]", "cannot spread INTEGER, want ARRAY"},
+		{`len(..."ab")`, "cannot spread STRING, want ARRAY"},
+		{"{...[1]}", "cannot spread ARRAY, want HASH"},
+		{"[...(1 + true)]", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(a) { a }; f(...[1, 2])", "wrong number of arguments. got=2, want=1"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		testBooleanObject(assert, evaluated, tt.expected)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				assert.Equal(expected, errObj.Message, tt.input)
+			} else {
+				assert.Equal(expected, evaluated.Inspect(), tt.input)
+			}
+		}
+	}
+
+	// The error points at the spread
+	evaluated := testEval("[1, ...2]")
+	if errObj, ok := evaluated.(*object.Error); assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+		assert.Equal(token.Position{Line: 1, Column: 5}, errObj.Pos)
 	}
 }
 
-func TestIfElseExpressions(t *testing.T) {
+func TestTailCalls(t *testing.T) {
+	// Without tail calls this recursion would need far more stack than this
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	runEvalTests(t, evaltest.TailCalls)
+	runEvalTests(t, []evaltest.Case{
+		{Input: "let f = fn(n) { while (true) { if (n == 0) { return 7 }; return f(n - 1) } }; f(200000)", Expected: 7},
+	})
+}
+
+func TestMaxCallDepth(t *testing.T) {
 	assert := assert.New(t)
+
+	testEval := func(input string) object.Object {
+		env := object.NewEnvironment()
+		env.SetMaxCallDepth(50)
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	evaluated := testEval("let f = fn(n) { 1 + f(n + 1) };\nf(0)")
+	errObj, ok := evaluated.(*object.Error)
+	if assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+		assert.Equal("maximum recursion depth exceeded", errObj.Message)
+		assert.Equal(token.Position{Line: 1, Column: 22}, errObj.Pos)
+		assert.Len(errObj.Stack, 51)
+		assert.Equal(object.StackFrame{Function: "f", Pos: token.Position{Line: 2, Column: 1}},
+			errObj.Stack[50])
+	}
+
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{"if (true) { 10 }", 10},
-		{"if (false) { 10 }", nil},
-		{"if (1) { 10 }", 10},
-		{"if (1 < 2) { 10 }", 10},
-		{"if (1 > 2) { 10 }", nil},
-		{"if (1 > 2) { 10 } else { 20 }", 20},
-		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(49)", 49},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(50)", "maximum recursion depth exceeded"},
+		// Tail calls don't count
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)", 0},
+		{`let f = fn() { f() + 1 }; try { f() } catch (e) { e["message"] }`, "maximum recursion depth exceeded"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(assert, evaluated, int64(integer))
-		} else {
-			testNullObject(assert, evaluated)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				assert.Equal(expected, errObj.Message, tt.input)
+			} else {
+				assert.Equal(expected, evaluated.Inspect(), tt.input)
+			}
 		}
 	}
 }
 
-func TestReturnStatements(t *testing.T) {
+func TestDefaultMaxCallDepth(t *testing.T) {
 	assert := assert.New(t)
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{"return 10;", 10},
-		{"return 10; 9", 10},
-		{"return 2 * 5; 9", 10},
-		{"9; return 2 * 5; 9;", 10},
-		{
-			`
-if (10 > 1) {
-  if (10 > 1) {
-    return 10;
-  }
 
-  return 1;
-}
-`,
-			10,
-		},
+	// Runaway recursion fails instead of overflowing the Go stack
+	evaluated := testEval("let f = fn(n) { 1 + f(n + 1) }; f(0)")
+	errObj, ok := evaluated.(*object.Error)
+	if assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated) {
+		assert.Equal("maximum recursion depth exceeded", errObj.Message)
+		assert.Len(errObj.Stack, DefaultMaxCallDepth+1)
 	}
+}
 
-	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		testIntegerObject(assert, evaluated, tt.expected)
-	}
+func TestClosures(t *testing.T) {
+	runEvalTests(t, evaltest.Closures)
 }
 
-func TestLetStatements(t *testing.T) {
+func TestAssignExpressions(t *testing.T) {
 	assert := assert.New(t)
 	tests := []struct {
 		input    string
-		expected int64
+		expected interface{}
 	}{
-		{"let a = 5; a;", 5},
-		{"let a = 5 * 5; a;", 25},
-		{"let a = 5; let b = a; b;", 5},
-		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{"let x = 1.5; x *= 2; x", 3.0},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let a = [1, 2, 3]; a[1] = 5; a", "[1, 5, 3]"},
+		{"let a = [1, 2, 3]; a[2] += 10", 13},
+		{`let h = {"a": 1}; h["b"] = 2; h`, "{a: 1, b: 2}"},
+		{`let h = {"a": 1}; h.a += 1; h.a`, 2},
+		{`let h = {}; h[[1, 2]] = true; h[[1, 2]]`, true},
+		// Closures update the binding they captured
+		{`let counter = fn() { let n = 0; fn() { n += 1 } }();
+		counter(); counter(); counter()`, 3},
+		{"let x = 1; let f = fn() { let x = 5; x = 6 }; f(); x", 1},
+		{"let x = 1; if (true) { x = 2 }; x", 2},
+		// Changing an array after using it as a key doesn't change the key
+		{"let k = [1]; let h = {k: 1}; k[0] = 2; h[[1]]", 1},
+		{"let a = [1]; a[0] = a; a", "[[...]]"},
+		{"let a = [1]; a[0] = a; a < a", false},
+		{"let a = [1]; a[0] = a; a <= a", true},
+		{"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a >= b", true},
+		{"let a = [1, 2]; a[0] = a; let b = [1, 3]; b[0] = b; a < b", true},
+		{"y = 1", "cannot assign to undeclared identifier: y"},
+		{"len = 1", "cannot assign to undeclared identifier: len"},
+		{"y += 1", "identifier not found: y"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1"},
+		{"let a = [1]; a[-1] = 2", "index out of range: -1"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{"let h = {}; h[fn() {}] = 1", "unusable as hash key: FUNCTION"},
+		{"let a = [1]; a[0] = a; {a: 1}", "unusable as hash key: ARRAY"},
+		{"let x = 1; x /= 0", "division by zero"},
+		{`let x = 1; x += "a"`, "type mismatch: INTEGER + STRING"},
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(assert, testEval(tt.input), tt.expected)
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case float64:
+			testFloatObject(assert, evaluated, expected)
+		case bool:
+			testBooleanObject(assert, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				assert.Equal(expected, errObj.Message, tt.input)
+			} else {
+				assert.Equal(expected, evaluated.Inspect(), tt.input)
+			}
+		}
 	}
 }
 
-func TestErrorHandling(t *testing.T) {
+func TestLoops(t *testing.T) {
+	assert := assert.New(t)
 	tests := []struct {
-		input           string
-		expectedMessage string
+		input    string
+		expected interface{}
 	}{
-		{
-			"5 + true;",
-			"type mismatch: INTEGER + BOOLEAN",
-		},
-		{
-			"5 + true; 5;",
-			"type mismatch: INTEGER + BOOLEAN",
-		},
-		{
-			"-true",
-			"unknown operator: -BOOLEAN",
-		},
-		{
-			"true + false;",
-			"unknown operator: BOOLEAN + BOOLEAN",
-		},
-		{
-			`"Hello" - "World"`,
-			"unknown operator: STRING - STRING",
-		},
-		{
-			"5; true + false; 5",
-			"unknown operator: BOOLEAN + BOOLEAN",
-		},
-		{
-			"if (10 > 1) { true + false; }",
-			"unknown operator: BOOLEAN + BOOLEAN",
-		},
-		{
-			`
-if (10 > 1) {
-  if (10 > 1) {
-    return true + false;
-  }
-
-  return 1;
-}
-`,
-			"unknown operator: BOOLEAN + BOOLEAN",
-		},
-		{
-			"foobar",
-			"identifier not found: foobar",
-		},
-		{
-			`"1" * -3`,
-			"negative argument error: STRING * -3",
-		},
-		{
-			`{"name": "Monkey"}[fn(x) { x }];`,
-			"unusable as hash key: FUNCTION",
-		},
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let i = 0; while (false) { i = 1 }; i", 0},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{`let s = ""; for (c in "héllo") { s = c + s }; s`, "olléh"},
+		{`let keys = []; for (k in {"b": 1, "a": 2}) { keys = push(keys, k) }; keys`, "[b, a]"},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break } }; i", 3},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue }; sum += x }; sum", 4},
+		// break and continue only affect the innermost loop
+		{`let n = 0;
+		for (i in [1, 2, 3]) { for (j in [1, 2, 3]) { if (j > i) { break }; n += 1 } };
+		n`, 6},
+		// return leaves the loop and the function
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } }; 0 }; f()", 20},
+		{"let f = fn() { while (true) { return 1 } }; f()", 1},
+		{"let f = fn() { for (x in []) { } }; f()", nil},
+		// A break in a finally block still leaves the loop
+		{"let i = 0; while (true) { i += 1; try { i } finally { break } }; i", 1},
+		// Each iteration has its own binding of the variable
+		{`let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }) }; fs[0]() + fs[1]()`, 3},
+		{"for (x in [1]) { }; x", "identifier not found: x"},
+		{"for (x in 5) { }", "not iterable: INTEGER"},
+		{"while (1 + true) { }", "type mismatch: INTEGER + BOOLEAN"},
+		// Loop signals from the value of a let or an assignment leave it unset
+		{"let i = 0; while (i < 3) { let x = if (true) { break }; i += 1 }; i", 0},
+		{"let n = 0; for (x in [1, 2, 3]) { let y = if (x == 2) { continue } else { x }; n += y }; n", 4},
+		{"let i = 0; let x = 0; while (i < 3) { i += 1; x = if (true) { break } }; [i, x]", "[1, 0]"},
+		{"let n = 0; for (x in [1, 2, 3]) { n += if (x == 2) { continue } else { x } }; n", 4},
+		{"let a = [0]; for (x in [1, 2]) { a[0] = if (x == 2) { break } else { x } }; a", "[1]"},
+		{"let f = fn() { let x = if (true) { return 1 }; 2 }; f()", 1},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
 	}
 
 	for _, tt := range tests {
-		assert := assert.New(t)
 		evaluated := testEval(tt.input)
-
-		errorObj, ok := evaluated.(*object.Error)
-		assert.True(ok, "evaluated was not an error, got %T(%+v)", evaluated, evaluated)
-		assert.Equal(tt.expectedMessage, errorObj.Message)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case nil:
+			testNullObject(assert, evaluated)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				assert.Equal(expected, errObj.Message, tt.input)
+			} else {
+				assert.Equal(expected, evaluated.Inspect(), tt.input)
+			}
+		}
 	}
 }
 
-func TestFunctionObject(t *testing.T) {
+func TestLongLoop(t *testing.T) {
 	assert := assert.New(t)
-	input := "fn(x) { x + 2; };"
 
-	evaluated := testEval(input)
-	fn, ok := evaluated.(*object.Function)
-	assert.True(ok, "evaluated was not a function, got %T(%+v)", evaluated, evaluated)
+	input := "let n = 0; for (x in range) { n += x }; n"
+	elements := make([]object.Object, 100000)
+	for i := range elements {
+		elements[i] = &object.Integer{Value: 1}
+	}
 
-	assert.Equal(1, len(fn.Parameters))
-	assert.Equal("x", fn.Parameters[0].String())
-	assert.Equal("(x + 2)", fn.Body.String())
+	env := object.NewEnvironment()
+	env.Set("range", &object.Array{Elements: elements})
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	testIntegerObject(assert, Eval(program, env), 100000)
 }
 
-func TestFunctionApplication(t *testing.T) {
-	assert := assert.New(t)
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{"let identity = fn(x) { x; }; identity(5);", 5},
-		{"let identity = fn(x) { return x; }; identity(5);", 5},
-		{"let double = fn(x) { x * 2; }; double(5);", 10},
-		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
-		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
-		{"fn(x) { x; }(5)", 5},
-	}
+func TestRecursiveFunctions(t *testing.T) {
+	runEvalTests(t, evaltest.RecursiveFunctions)
+}
 
-	for _, tt := range tests {
-		testIntegerObject(assert, testEval(tt.input), tt.expected)
-	}
+func TestArrayExpressions(t *testing.T) {
+	runEvalTests(t, evaltest.ArrayExpressions)
 }
 
-func TestClosures(t *testing.T) {
+func TestHashLiteralOrder(t *testing.T) {
 	assert := assert.New(t)
-	input := `
-let newAdder = fn(x) {
-  fn(y) { x + y };
-};
 
-let addTwo = newAdder(2);
-addTwo(2);
-	`
-	testIntegerObject(assert, testEval(input), 4)
+	// Pairs are evaluated in source order, so the first error wins
+	assert.Equal("ERROR: 1:9: type mismatch: INTEGER + BOOLEAN",
+		testEval(`{"a": 1 + true, "b": len(1)}`).Inspect())
 }
 
-func TestArrayLiterals(t *testing.T) {
+func TestHashExpressions(t *testing.T) {
+	runEvalTests(t, evaltest.HashExpressions)
+}
+
+func TestHashIndexComparesKeys(t *testing.T) {
 	assert := assert.New(t)
-	input := "[1, 2 * 2, 3 + 3]"
 
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Array)
-	assert.True(ok, "object is not Array. got=%T (%+v)", evaluated, evaluated)
-	assert.Equal(3, len(result.Elements))
+	// Every key collides, so only comparing the keys tells them apart
+	hash := object.NewHashWithHasher(func(object.HashTable) object.HashKey {
+		return object.HashKey{}
+	})
+	lookedUp := &object.String{Value: "looked up"}
+	hash.Set(&object.String{Value: "stored"}, TRUE)
+
+	testNullObject(assert, evalHashIndexExpression(hash, lookedUp))
+
+	hash.Set(lookedUp, FALSE)
+	assert.Equal(FALSE, evalHashIndexExpression(hash, &object.String{Value: "looked up"}))
+}
 
-	testIntegerObject(assert, result.Elements[0], 1)
-	testIntegerObject(assert, result.Elements[1], 4)
-	testIntegerObject(assert, result.Elements[2], 6)
+func TestBuiltinFunctions(t *testing.T) {
+	runEvalTests(t, evaltest.BuiltinFunctions)
 }
 
-func TestArrayIndexExpressions(t *testing.T) {
+func TestDefineBuiltin(t *testing.T) {
 	assert := assert.New(t)
+
+	double := &object.Builtin{
+		Name:  "math.double",
+		Arity: 1,
+		Fn: func(args ...object.Object) object.Object {
+			if err := CheckArgType("math.double", args[0], object.INTEGER_OBJ); err != nil {
+				return err
+			}
+			return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+		},
+	}
+	sum := &object.Builtin{
+		Name:     "math.sum",
+		Variadic: true,
+		Fn: func(args ...object.Object) object.Object {
+			total := int64(0)
+			for _, arg := range args {
+				total += arg.(*object.Integer).Value
+			}
+			return &object.Integer{Value: total}
+		},
+	}
+	length := &object.Builtin{
+		Name:  "len",
+		Arity: 1,
+		Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: "overridden"}
+		},
+	}
+
+	env := object.NewEnvironment()
+	for _, builtin := range []*object.Builtin{double, sum, length} {
+		assert.NoError(DefineBuiltin(env, builtin))
+	}
+	assert.EqualError(DefineBuiltin(env, &object.Builtin{Name: "len.x"}), "len is not a namespace")
+	assert.EqualError(DefineBuiltin(env, &object.Builtin{Name: "math."}), `invalid builtin name "math."`)
+
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{
-			"[1, 2, 3][0]",
-			1,
-		},
-		{
-			"[1, 2, 3][1]",
-			2,
-		},
-		{
-			"[1, 2, 3][2]",
-			3,
-		},
-		{
-			"let i = 0; [1][i];",
-			1,
-		},
-		{
-			"[1, 2, 3][1 + 1];",
-			3,
-		},
-		{
-			"let myArray = [1, 2, 3]; myArray[2];",
-			3,
-		},
-		{
-			"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
-			6,
-		},
-		{
-			"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
-			2,
-		},
-		{
-			"[1, 2, 3][3]",
-			nil,
-		},
-		{
-			"[1, 2, 3][-1]",
-			nil,
-		},
+		{`math.double(21)`, 42},
+		{`math.sum()`, 0},
+		{`math.sum(1, 2, 3)`, 6},
+		{`math.double(1, 2)`, "wrong number of arguments. got=2, want=1"},
+		{`math.double("1")`, "argument to `math.double` must be INTEGER, got STRING"},
+		{`math.missing`, "module math has no member missing"},
+		{`len([1])`, "overridden"},
+		{`let len = fn(x) { 1 }; len([1, 2])`, 1},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(assert, evaluated, int64(integer))
-		} else {
-			testNullObject(assert, evaluated)
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), object.NewEnclosingEnvironment(env))
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(assert, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.Error:
+				assert.Equal(expected, obj.Message, tt.input)
+			case *object.String:
+				assert.Equal(expected, obj.Value, tt.input)
+			default:
+				assert.Fail("unexpected object", "%s: got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
 		}
 	}
+
+	// Environments without definitions keep the default builtins
+	testIntegerObject(assert, testEval(`len([1])`), 1)
+
+	// Namespaces of enclosing environments are shadowed, not extended
+	inner := object.NewEnclosingEnvironment(env)
+	assert.NoError(DefineBuiltin(inner, &object.Builtin{Name: "math.triple"}))
+	math, _ := env.Get("math")
+	assert.NotContains(math.(*object.Module).Exports, "triple")
+	innerMath, _ := inner.Get("math")
+	assert.Len(innerMath.(*object.Module).Exports, 1)
+	assert.Contains(innerMath.(*object.Module).Exports, "triple")
+
+	// Imported modules are not namespaces
+	env.Set("lib", &object.Module{Path: "lib.mk", Exports: map[string]object.Object{}})
+	assert.EqualError(DefineBuiltin(env, &object.Builtin{Name: "lib.f"}), "lib is not a namespace")
 }
 
-func TestHashLiterals(t *testing.T) {
+func TestCheckArgType(t *testing.T) {
 	assert := assert.New(t)
-	input := `let two = "two";
-	{
-		"one": 10 - 9,
-		two: 1 + 1,
-		"thr" + "ee": 6 / 2,
-		4: 4,
-		true: 5,
-		false: 6
-	}`
 
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Hash)
-	assert.True(ok, "Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	assert.Nil(CheckArgType("f", &object.String{Value: "x"}, object.INTEGER_OBJ, object.STRING_OBJ))
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
-	}
-
-	assert.Equal(len(expected), len(result.Pairs))
-
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
-		assert.True(ok, "no pair for given key in Pairs")
-
-		testIntegerObject(assert, pair.Value, expectedValue)
+	err := CheckArgType("f", TRUE, object.INTEGER_OBJ, object.STRING_OBJ)
+	if assert.NotNil(err) {
+		assert.Equal("argument to `f` must be INTEGER or STRING, got BOOLEAN", err.Message)
 	}
 }
 
-func TestHashIndexExpressions(t *testing.T) {
+func TestImport(t *testing.T) {
 	assert := assert.New(t)
-	tests := []struct {
-		input    string
-		expected interface{}
-	}{
-		{
-			`{"foo": 5}["foo"]`,
-			5,
-		},
-		{
-			`{"foo": 5}["bar"]`,
-			nil,
-		},
-		{
-			`let key = "foo"; {"foo": 5}[key]`,
-			5,
-		},
-		{
-			`{}["foo"]`,
-			nil,
-		},
-		{
-			`{5: 5}[5]`,
-			5,
-		},
-		{
-			`{true: 5}[true]`,
-			5,
-		},
-		{
-			`{false: 5}[false]`,
-			5,
-		},
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"math.mk":       `let square = fn(x) { x * x }; let answer = square(6) + 6;`,
+		"lib/util.mk":   `let helper = import "helper.mk"; let twice = fn(x) { helper["inc"](helper["inc"](x)) };`,
+		"lib/helper.mk": `let inc = fn(x) { x + 1 };`,
+		"a.mk":          `let b = import "b.mk";`,
+		"b.mk":          `let a = import "a.mk";`,
+		"broken.mk":     `let x 5;`,
+		"failing.mk":    `let x = 1 + true;`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(os.WriteFile(path, []byte(source), 0o644))
 	}
 
-	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(assert, evaluated, int64(integer))
-		} else {
-			testNullObject(assert, evaluated)
-		}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
 	}
-}
 
-func TestBuiltinFunctions(t *testing.T) {
-	assert := assert.New(t)
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{`len("")`, 0},
-		{`len("four")`, 4},
-		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` not supported, got INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`let m = import "` + path("math.mk") + `"; m["answer"]`, 42},
+		{`let m = import "` + path("math.mk") + `"; m["square"](3)`, 9},
+		{`(import "` + path("lib/util.mk") + `")["twice"](1)`, 3},
+		{`(import "` + path("math.mk") + `") == (import "` + path("math.mk") + `")`, true},
+		{`(import "` + path("math.mk") + `")["missing"]`,
+			"module " + path("math.mk") + " has no member missing"},
+		{`import 5`, "import path must be STRING, got INTEGER"},
+		{`import "` + path("a.mk") + `"`,
+			"import cycle: " + path("a.mk") + " -> " + path("b.mk") + " -> " + path("a.mk")},
+		{`import "` + path("broken.mk") + `"`,
+			"cannot import " + path("broken.mk") + ": " + path("broken.mk") +
+				":1:7: expected next token to be ASSIGN, got INT instead"},
+		{`import "` + path("failing.mk") + `"`, "type mismatch: INTEGER + BOOLEAN"},
 	}
 
 	for _, tt := range tests {
@@ -487,14 +697,90 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(assert, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(assert, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*object.Error)
-			assert.True(ok, "object is not Error. got=%T (+%v)")
-			assert.Equal(tt.expected, errObj.Message)
+			if assert.True(ok, "object is not Error. got=%T (%+v)", evaluated, evaluated) {
+				assert.Equal(expected, errObj.Message)
+			}
 		}
 	}
 }
 
+func TestImportRelativeToImporter(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "lib.mk"), []byte(`let value = 7;`), 0o644))
+
+	l := lexer.NewWithFilename(`(import "lib.mk")["value"]`, filepath.Join(dir, "main.mk"))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	testIntegerObject(assert, Eval(program, object.NewEnvironment()), 7)
+}
+
+func TestConcurrentImports(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"slow.mk": `let i = 0; while (i < 50000) { i += 1 }; let value = i;`,
+		"a.mk":    `let i = 0; while (i < 2000) { i += 1 }; let b = import "b.mk";`,
+		"b.mk":    `let i = 0; while (i < 2000) { i += 1 }; let a = import "a.mk";`,
+	}
+	for name, source := range files {
+		assert.NoError(os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644))
+	}
+
+	evalAll := func(env *object.Environment, inputs ...string) []object.Object {
+		results := make([]object.Object, len(inputs))
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i, input := range inputs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				program := parser.New(lexer.New(input)).ParseProgram()
+				<-start
+				results[i] = Eval(program, object.NewEnclosingEnvironment(env))
+			}()
+		}
+		close(start)
+		wg.Wait()
+		return results
+	}
+
+	// Importers of a file being evaluated wait for it instead of seeing a cycle
+	env := object.NewEnvironment()
+	slow := `import "` + filepath.Join(dir, "slow.mk") + `"`
+	results := evalAll(env, slow, slow, slow, slow, slow, slow, slow, slow)
+	for _, result := range results {
+		if module, ok := result.(*object.Module); assert.True(ok, "result is not Module. got=%T (%+v)", result, result) {
+			assert.Same(results[0], module)
+		}
+	}
+
+	// A cycle between imports started concurrently is still reported
+	results = evalAll(object.NewEnvironment(),
+		`import "`+filepath.Join(dir, "a.mk")+`"`,
+		`import "`+filepath.Join(dir, "b.mk")+`"`)
+	for _, result := range results {
+		if errObj, ok := result.(*object.Error); assert.True(ok, "result is not Error. got=%T (%+v)", result, result) {
+			assert.True(strings.HasPrefix(errObj.Message, "import cycle: "), errObj.Message)
+		}
+	}
+}
+
+func runEvalTests(t *testing.T, tests []evaltest.Case) {
+	assert := assert.New(t)
+
+	for _, tt := range tests {
+		evaltest.Check(assert, tt, testEval(tt.Input))
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -510,6 +796,13 @@ func testIntegerObject(assert *assert.Assertions, obj object.Object, expected in
 	assert.Equal(expected, result.Value, "object has wrong value")
 }
 
+func testFloatObject(assert *assert.Assertions, obj object.Object, expected float64) {
+	result, ok := obj.(*object.Float)
+	if assert.True(ok, "object is not Float, got=%T (%+v)", obj, obj) {
+		assert.Equal(expected, result.Value, "object has wrong value")
+	}
+}
+
 func testBooleanObject(assert *assert.Assertions, obj object.Object, expected bool) {
 	result, ok := obj.(*object.Boolean)
 	assert.True(ok, "object is not Boolean, got=%T (%+v)", obj, obj)
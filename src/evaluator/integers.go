@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+)
+
+// Integer arithmetic is done on int64 until a result overflows, then it is
+// redone on big.Int. object.IntegerFromBig turns the results back into an
+// Integer when they fit again.
+//
+// Division floors, rounding towards negative infinity, and the result of
+// modulo takes the sign of the divisor, so a == (a / b) * b + a % b holds for
+// any sign. Callers check for a zero divisor.
+
+func addInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0)
+}
+
+func subInt64(a, b int64) (int64, bool) {
+	diff := a - b
+	return diff, (a >= 0 && b < 0 && diff < 0) || (a < 0 && b > 0 && diff >= 0)
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+	product := a * b
+	overflow := product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64)
+	return product, overflow
+}
+
+func floorDivInt64(a, b int64) (int64, bool) {
+	if a == math.MinInt64 && b == -1 {
+		return 0, true
+	}
+
+	quotient := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		quotient--
+	}
+	return quotient, false
+}
+
+func floorModInt64(a, b int64) int64 {
+	remainder := a % b
+	if remainder != 0 && (remainder < 0) != (b < 0) {
+		remainder += b
+	}
+	return remainder
+}
+
+func floorModFloat64(a, b float64) float64 {
+	remainder := math.Mod(a, b)
+	if remainder != 0 && (remainder < 0) != (b < 0) {
+		remainder += b
+	}
+	return remainder
+}
+
+func evalBigIntegerInfixExpression(operator string, left, right *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return object.IntegerFromBig(new(big.Int).Add(left, right))
+	case "-":
+		return object.IntegerFromBig(new(big.Int).Sub(left, right))
+	case "*":
+		return object.IntegerFromBig(new(big.Int).Mul(left, right))
+	case "/", "%":
+		quotient, remainder := new(big.Int).QuoRem(left, right, new(big.Int))
+		if remainder.Sign() != 0 && remainder.Sign() != right.Sign() {
+			quotient.Sub(quotient, big.NewInt(1))
+			remainder.Add(remainder, right)
+		}
+		if operator == "%" {
+			return object.IntegerFromBig(remainder)
+		}
+		return object.IntegerFromBig(quotient)
+	case "<":
+		return nativeBoolToBooleanObject(left.Cmp(right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(left.Cmp(right) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(left.Cmp(right) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(left.Cmp(right) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(left.Cmp(right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, operator, object.INTEGER_OBJ)
+	}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	default:
+		return nil
+	}
+}
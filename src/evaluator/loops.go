@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// Loops are statements, they produce no value. A return or an error in the
+// body ends the loop and is passed on.
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if result, done := evalLoopBody(ws.Body, env); done {
+			return result
+		}
+	}
+}
+
+// evalForStatement runs the body for each element of an array, character of a
+// string or key of a hash. Each iteration binds the variable in its own scope,
+// so closures created in the body see the element of their iteration.
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	next := iterate(iterable)
+	if next == nil {
+		return newError("not iterable: %s", iterable.Type())
+	}
+
+	for element, ok := next(); ok; element, ok = next() {
+		iterationEnv := object.NewEnclosingEnvironment(env)
+		iterationEnv.Set(fs.Variable.Value, element)
+
+		if result, done := evalLoopBody(fs.Body, iterationEnv); done {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// evalLoopBody runs one iteration, reporting whether the loop is done and
+// with which result.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	switch result := Eval(body, env).(type) {
+	case *object.Break:
+		return nil, true
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// iterate returns a function producing the elements of obj one at a time, or
+// nil if obj can't be iterated. Arrays are read as the loop goes, so it sees
+// elements assigned by the body. Hashes iterate over the keys they had when
+// the loop started.
+func iterate(obj object.Object) func() (object.Object, bool) {
+	var i int
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		return func() (object.Object, bool) {
+			if i >= len(obj.Elements) {
+				return nil, false
+			}
+			i++
+			return obj.Elements[i-1], true
+		}
+	case *object.String:
+		chars := []rune(obj.Value)
+		return func() (object.Object, bool) {
+			if i >= len(chars) {
+				return nil, false
+			}
+			i++
+			return &object.String{Value: string(chars[i-1])}, true
+		}
+	case *object.Hash:
+		keys := obj.Keys()
+		return func() (object.Object, bool) {
+			if i >= len(keys) {
+				return nil, false
+			}
+			i++
+			return keys[i-1], true
+		}
+	default:
+		return nil
+	}
+}
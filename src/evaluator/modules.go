@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func evalImportExpression(node *ast.ImportExpression, env *object.Environment) object.Object {
+	path := Eval(node.Path, env)
+	if isError(path) {
+		return path
+	}
+
+	str, ok := path.(*object.String)
+	if !ok {
+		return newError("import path must be STRING, got %s", path.Type())
+	}
+
+	return importModule(resolveImportPath(str.Value, node.Pos().Filename), env)
+}
+
+// resolveImportPath makes path absolute. Relative paths are relative to the
+// directory of the importing file, or the working directory when the code
+// doesn't come from a file.
+func resolveImportPath(path, importer string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(importer), path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// importModule returns the module at the absolute path. Modules are cached by
+// path in the module cache of env, so every file is evaluated once.
+func importModule(path string, env *object.Environment) object.Object {
+	module, cycle := env.Modules().Import(path, env.ModuleLoad(), func(load *object.ModuleLoad) object.Object {
+		return loadModule(load, env)
+	})
+	if cycle != nil {
+		return newError("import cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return module
+}
+
+func loadModule(load *object.ModuleLoad, importer *object.Environment) object.Object {
+	path := load.Path
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return newError("cannot import %s: %s", path, err)
+	}
+
+	l := lexer.NewWithFilename(string(source), path)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostics := make([]string, len(p.Errors()))
+		for i, d := range p.Errors() {
+			diagnostics[i] = d.String()
+		}
+		return newError("cannot import %s: %s", path, strings.Join(diagnostics, "; "))
+	}
+
+	env := object.NewModuleEnvironment(importer, load)
+	if result := Eval(program, env); isError(result) {
+		return result
+	}
+
+	return &object.Module{Path: path, Exports: env.Bindings()}
+}
+
+func evalModuleIndexExpression(module *object.Module, index object.Object) object.Object {
+	name, ok := index.(*object.String)
+	if !ok {
+		return newError("module members are accessed by STRING, got %s", index.Type())
+	}
+
+	member, ok := module.Exports[name.Value]
+	if !ok {
+		return newError("module %s has no member %s", module.Path, name.Value)
+	}
+
+	return member
+}
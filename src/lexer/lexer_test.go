@@ -15,8 +15,9 @@ func TestNextToken(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5;
+	!-/*%5;
 	5 < 10 > 5;
+	5 <= 10 >= 5;
 
 	if (5 < 10) {
 		return true;
@@ -26,10 +27,17 @@ func TestNextToken(t *testing.T) {
 
 	10 == 10;
 	10 != 9;
+	a && b || c;
 	"foobar"
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"}
+	try catch finally throw import
+	while for in break continue
+	math.abs
+	1.5 2e10 3.0E-2 4.e
+	x += 1; x -= 1; x *= 2; x /= 2;
+	...rest ..
 	`
 
 	tests := []struct {
@@ -76,6 +84,7 @@ func TestNextToken(t *testing.T) {
 		{token.MINUS, "-"},
 		{token.SLASH, "/"},
 		{token.ASTERISK, "*"},
+		{token.PERCENT, "%"},
 		{token.INT, "5"},
 		{token.SEMICOLON, ";"},
 		{token.INT, "5"},
@@ -84,6 +93,12 @@ func TestNextToken(t *testing.T) {
 		{token.GT, ">"},
 		{token.INT, "5"},
 		{token.SEMICOLON, ";"},
+		{token.INT, "5"},
+		{token.LT_EQ, "<="},
+		{token.INT, "10"},
+		{token.GT_EQ, ">="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
 		{token.IF, "if"},
 		{token.LPAREN, "("},
 		{token.INT, "5"},
@@ -109,6 +124,12 @@ func TestNextToken(t *testing.T) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "a"},
+		{token.AND, "&&"},
+		{token.IDENTIFIER, "b"},
+		{token.OR, "||"},
+		{token.IDENTIFIER, "c"},
+		{token.SEMICOLON, ";"},
 		{token.STRING, "foobar"},
 		{token.STRING, "foo bar"},
 		{token.LBRACKET, "["},
@@ -122,6 +143,45 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.IMPORT, "import"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IDENTIFIER, "math"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "abs"},
+		{token.FLOAT, "1.5"},
+		{token.FLOAT, "2e10"},
+		{token.FLOAT, "3.0E-2"},
+		{token.INT, "4"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "e"},
+		{token.IDENTIFIER, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "rest"},
+		{token.DOT, "."},
+		{token.DOT, "."},
 		{token.EOF, ""},
 	}
 
@@ -139,3 +199,39 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x == "two words"
+	fn`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Line: 1, Column: 1}},
+		{token.IDENTIFIER, token.Position{Filename: "test.mk", Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Line: 1, Column: 7}},
+		{token.INT, token.Position{Filename: "test.mk", Line: 1, Column: 9}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Line: 1, Column: 10}},
+		{token.IDENTIFIER, token.Position{Filename: "test.mk", Line: 2, Column: 3}},
+		{token.EQ, token.Position{Filename: "test.mk", Line: 2, Column: 5}},
+		{token.STRING, token.Position{Filename: "test.mk", Line: 2, Column: 8}},
+		{token.FUNCTION, token.Position{Filename: "test.mk", Line: 3, Column: 2}},
+		{token.EOF, token.Position{Filename: "test.mk", Line: 3, Column: 4}},
+	}
+
+	l := NewWithFilename(input, "test.mk")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests [%d] - tokentype wrong. expected=%v, got %v", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests [%d] - position wrong. expected=%s, got %s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
@@ -1,21 +1,109 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/repl"
 )
 
+const usage = `Usage:
+  monkey                          start the interactive REPL
+  monkey <file> [args...]         run a script
+  monkey -e <expr> [args...]      evaluate an expression and print its value
+
+Extra arguments are available to the script as the ` + "`args`" + ` array.
+`
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit status: 0 on
+// success, 1 when the program fails to parse or run and 2 on bad usage.
+func run(arguments []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { io.WriteString(stderr, usage) }
+	expr := flags.String("e", "", "evaluate `expr` instead of a file")
+
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+
+	positional := flags.Args()
+
+	switch {
+	case *expr != "":
+		result, status := execute(*expr, "-e", positional, stdout, stderr)
+		if result != nil && result != evaluator.NULL {
+			fmt.Fprintln(stdout, result.Inspect())
+		}
+		return status
+
+	case len(positional) > 0:
+		filename := positional[0]
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return 1
+		}
+
+		_, status := execute(string(source), filename, positional[1:], stdout, stderr)
+		return status
+
+	default:
+		startRepl(stdin, stdout)
+		return 0
+	}
+}
+
+func execute(source, filename string, args []string, stdout, stderr io.Writer) (object.Object, int) {
+	l := lexer.NewWithFilename(source, filename)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintDiagnostics(stderr, source, p.Errors())
+		return nil, 1
+	}
+
+	env := object.NewEnvironment()
+	env.Set("args", scriptArgs(args))
+
+	evaluated := evaluator.Eval(program, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(stderr, err.Inspect())
+		io.WriteString(stderr, err.StackTrace())
+		return nil, 1
+	}
+
+	return evaluated, 0
+}
+
+func scriptArgs(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func startRepl(in io.Reader, out io.Writer) {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n",
+	fmt.Fprintf(out, "Hello %s! This is the Monkey programming language!\n",
 		user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Fprintf(out, "Feel free to type in commands\n")
+	repl.Start(in, out)
 }
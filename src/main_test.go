@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.mk")
+	broken := filepath.Join(dir, "broken.mk")
+	failing := filepath.Join(dir, "failing.mk")
+
+	assert.NoError(os.WriteFile(script, []byte("let n = len(args);\nn * 2"), 0o644))
+	assert.NoError(os.WriteFile(broken, []byte("let x 5;"), 0o644))
+	assert.NoError(os.WriteFile(failing, []byte("let f = fn() { 1 + true };\nf();"), 0o644))
+
+	tests := []struct {
+		args           []string
+		expectedStatus int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{[]string{"-e", "1 + 2"}, 0, "3\n", ""},
+		{[]string{"-e", "args", "a", "b"}, 0, "[a, b]\n", ""},
+		{[]string{"-e", "if (false) { 1 }"}, 0, "", ""},
+		{[]string{"-e", "-true"}, 1, "", "ERROR: -e:1:1: unknown operator: -BOOLEAN\n"},
+		{[]string{script, "x", "y"}, 0, "", ""},
+		{[]string{broken}, 1, "", broken + ":1:7: expected next token to be ASSIGN, got INT instead\n"},
+		{[]string{failing}, 1, "", "ERROR: " + failing + ":1:18: type mismatch: INTEGER + BOOLEAN\n" +
+			"\tat f (" + failing + ":2:1)\n"},
+		{[]string{filepath.Join(dir, "missing.mk")}, 1, "", "no such file or directory"},
+		{[]string{"-unknown"}, 2, "", "Usage:"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+
+		assert.Equal(tt.expectedStatus, status, tt.args)
+		assert.Equal(tt.expectedStdout, stdout.String(), tt.args)
+		if tt.expectedStderr == "" {
+			assert.Empty(stderr.String(), tt.args)
+		} else {
+			assert.Contains(stderr.String(), tt.expectedStderr, tt.args)
+		}
+	}
+}
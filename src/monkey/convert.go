@@ -0,0 +1,335 @@
+package monkey
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"monkey/evaluator"
+	"monkey/object"
+	"reflect"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+// ToObject converts a Go value into a Monkey object:
+//   - nil becomes null, and bools, integers (*big.Int included), floats and
+//     strings their Monkey types
+//   - slices and arrays become arrays, maps become hashes
+//   - functions become builtins that convert their arguments from Monkey and
+//     their results back; a non-nil trailing error result becomes a Monkey error
+//   - values that already are an object.Object are used as is
+func ToObject(value interface{}) (object.Object, error) {
+	if value == nil {
+		return evaluator.NULL, nil
+	}
+	switch value := value.(type) {
+	case object.Object:
+		return value, nil
+	case *big.Int:
+		if value == nil {
+			return evaluator.NULL, nil
+		}
+		return object.IntegerFromBig(new(big.Int).Set(value)), nil
+	}
+	return toObject(reflect.ValueOf(value))
+}
+
+func toObject(v reflect.Value) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return evaluator.NativeBoolToBooleanObject(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return object.IntegerFromBig(new(big.Int).SetUint64(v.Uint())), nil
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			el, err := ToObject(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		hash := &object.Hash{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := ToObject(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if !object.Hashable(key) {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+			value, err := ToObject(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			hash.Set(key, value)
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return wrapFunc(v), nil
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return ToObject(v.Elem().Interface())
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to a Monkey value", v.Type())
+}
+
+// FromObject converts a Monkey object into a Go value. Integers and floats
+// become int64 and float64, or *big.Int for integers that don't fit in 64
+// bits. Strings, bools and null become string, bool and nil, arrays become
+// []interface{} and hashes map[interface{}]interface{}. Objects that have no
+// Go equivalent, such as functions, are returned as is, and so are hash keys
+// that Go maps can't hold, such as arrays. An array or hash containing itself
+// becomes a slice or map containing itself.
+func FromObject(obj object.Object) interface{} {
+	return fromObject(obj, map[object.Object]interface{}{})
+}
+
+// fromObject converts obj, reusing the values in converted for the arrays
+// and hashes it converted already.
+func fromObject(obj object.Object, converted map[object.Object]interface{}) interface{} {
+	if value, ok := converted[obj]; ok {
+		return value
+	}
+
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInteger:
+		return new(big.Int).Set(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		converted[obj] = elements
+		for i, el := range obj.Elements {
+			elements[i] = fromObject(el, converted)
+		}
+		return elements
+	case *object.Hash:
+		hash := make(map[interface{}]interface{}, obj.Len())
+		converted[obj] = hash
+		for _, pair := range obj.Pairs() {
+			var key interface{} = pair.Key
+			if convertedKey := fromObject(pair.Key, converted); reflect.TypeOf(convertedKey).Comparable() {
+				key = convertedKey
+			}
+			hash[key] = fromObject(pair.Value, converted)
+		}
+		return hash
+	default:
+		return obj
+	}
+}
+
+// wrapFunc turns a Go function into a builtin, converting its arguments to
+// the parameter types of fn.
+func wrapFunc(fn reflect.Value) *object.Builtin {
+	t := fn.Type()
+
+	numIn := t.NumIn()
+	arity := numIn
+	if t.IsVariadic() {
+		arity--
+	}
+
+	return &object.Builtin{
+		Arity:    arity,
+		Variadic: t.IsVariadic(),
+		Fn: func(args ...object.Object) object.Object {
+			in := make([]reflect.Value, len(args))
+			for i, arg := range args {
+				paramType := t.In(min(i, numIn-1))
+				if t.IsVariadic() && i >= numIn-1 {
+					paramType = paramType.Elem()
+				}
+
+				v, err := toGoValue(arg, paramType)
+				if err != nil {
+					return newError("argument %d: %s", i, err)
+				}
+				in[i] = v
+			}
+
+			out := fn.Call(in)
+
+			if n := t.NumOut(); n > 0 && t.Out(n-1) == errorType {
+				if err := out[n-1]; !err.IsNil() {
+					return newError("%s", err.Interface().(error))
+				}
+				out = out[:n-1]
+			}
+
+			switch len(out) {
+			case 0:
+				return evaluator.NULL
+			case 1:
+				return toResultObject(out[0])
+			default:
+				elements := make([]object.Object, len(out))
+				for i, v := range out {
+					elements[i] = toResultObject(v)
+				}
+				return &object.Array{Elements: elements}
+			}
+		},
+	}
+}
+
+func toResultObject(v reflect.Value) object.Object {
+	obj, err := ToObject(v.Interface())
+	if err != nil {
+		return newError("%s", err)
+	}
+	return obj
+}
+
+// toGoValue converts obj to a value of type t. Objects are passed as is only
+// to object types, an interface{} gets the Go value.
+func toGoValue(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t.Implements(objectType) && reflect.TypeOf(obj).AssignableTo(t) {
+		v := reflect.New(t).Elem()
+		v.Set(reflect.ValueOf(obj))
+		return v, nil
+	}
+
+	if t == bigIntType && obj.Type() == object.INTEGER_OBJ {
+		if integer, ok := obj.(*object.Integer); ok {
+			return reflect.ValueOf(big.NewInt(integer.Value)), nil
+		}
+	}
+
+	value := FromObject(obj)
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use null as %s", t)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	result := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := value.(int64); ok && !result.OverflowInt(n) {
+			result.SetInt(n)
+			return result, nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := value.(int64); ok && n >= 0 && !result.OverflowUint(uint64(n)) {
+			result.SetUint(uint64(n))
+			return result, nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch n := value.(type) {
+		case int64:
+			result.SetFloat(float64(n))
+			return result, nil
+		case float64:
+			result.SetFloat(n)
+			return result, nil
+		}
+
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			result.SetString(s)
+			return result, nil
+		}
+
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			result.SetBool(b)
+			return result, nil
+		}
+
+	case reflect.Slice:
+		if array, ok := obj.(*object.Array); ok {
+			result = reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+			for i, el := range array.Elements {
+				v, err := toGoValue(el, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				result.Index(i).Set(v)
+			}
+			return result, nil
+		}
+
+	case reflect.Map:
+		if hash, ok := obj.(*object.Hash); ok {
+			result = reflect.MakeMapWithSize(t, hash.Len())
+			for _, pair := range hash.Pairs() {
+				k, err := toGoValue(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				v, err := toGoValue(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				result.SetMapIndex(k, v)
+			}
+			return result, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,184 @@
+// Package monkey embeds the Monkey interpreter in Go programs.
+//
+//	interp := monkey.New()
+//	interp.Set("limit", 10)
+//	interp.Eval(`let allowed = fn(n) { n < limit };`)
+//	ok, err := interp.Call("allowed", 3)
+//
+// Values cross the boundary as plain Go values, see ToObject and FromObject.
+package monkey
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"reflect"
+	"strings"
+)
+
+// Interpreter evaluates Monkey code in an environment that persists between
+// calls, so later code sees the bindings made by earlier code. Modules are
+// cached per interpreter and see its builtins.
+type Interpreter struct {
+	builtins *object.Environment
+	env      *object.Environment
+}
+
+// Option configures an Interpreter, see New.
+type Option func(*Interpreter)
+
+// WithMaxCallDepth limits how many function calls can be active at once.
+// Deeper recursion fails with a runtime error, the default limit being
+// evaluator.DefaultMaxCallDepth.
+func WithMaxCallDepth(depth int) Option {
+	return func(i *Interpreter) {
+		i.builtins.SetMaxCallDepth(depth)
+	}
+}
+
+func New(options ...Option) *Interpreter {
+	builtins := object.NewEnvironment()
+	builtins.ShareWithModules()
+
+	i := &Interpreter{builtins: builtins}
+	for _, option := range options {
+		option(i)
+	}
+	i.env = object.NewEnclosingEnvironment(builtins)
+	return i
+}
+
+// ParseError is returned when the source is not valid Monkey.
+type ParseError struct {
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return "parse error: " + strings.Join(messages, "; ")
+}
+
+// RuntimeError is returned when evaluation fails with a Monkey error.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Err.Pos.IsValid() {
+		return fmt.Sprintf("runtime error: %s: %s", e.Err.Pos, e.Err.Message)
+	}
+	return "runtime error: " + e.Err.Message
+}
+
+// Eval runs src and returns the value of its last statement converted with
+// FromObject.
+func (i *Interpreter) Eval(src string) (interface{}, error) {
+	l := lexer.New(src)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Diagnostics: p.Errors()}
+	}
+
+	return result(evaluator.Eval(program, i.env))
+}
+
+// Set binds name to value, converted with ToObject.
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+
+	i.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the value bound to name, converted with FromObject.
+func (i *Interpreter) Get(name string) (interface{}, bool) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, false
+	}
+	return FromObject(obj), true
+}
+
+// Register makes builtin available to the programs of this interpreter under
+// builtin.Name, overriding any builtin of that name. Dotted names such as
+// "math.abs" place it in a namespace. Programs can still shadow it with `let`.
+func (i *Interpreter) Register(builtin *object.Builtin) error {
+	return evaluator.DefineBuiltin(i.builtins, builtin)
+}
+
+// RegisterFunc registers the Go function fn as a builtin called name. Its
+// arity and argument conversions follow from the signature of fn, see
+// ToObject.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return fmt.Errorf("cannot register %T as a function", fn)
+	}
+
+	obj, err := ToObject(fn)
+	if err != nil {
+		return err
+	}
+
+	builtin := obj.(*object.Builtin)
+	builtin.Name = name
+	return i.Register(builtin)
+}
+
+// Call calls the function bound to fnName, or the builtin of that name, with
+// args converted with ToObject. Dotted names call into namespaces and modules.
+func (i *Interpreter) Call(fnName string, args ...interface{}) (interface{}, error) {
+	fn, ok := i.lookup(fnName)
+	if !ok {
+		return nil, fmt.Errorf("function not found: %s", fnName)
+	}
+
+	objects := make([]object.Object, len(args))
+	for idx, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", idx, err)
+		}
+		objects[idx] = obj
+	}
+
+	return result(evaluator.ApplyFunction(fn, objects))
+}
+
+func (i *Interpreter) lookup(name string) (object.Object, bool) {
+	path := strings.Split(name, ".")
+
+	obj, ok := i.env.Get(path[0])
+	if !ok {
+		obj, ok = evaluator.LookupBuiltin(path[0])
+	}
+
+	for _, member := range path[1:] {
+		if !ok {
+			break
+		}
+		module, isModule := obj.(*object.Module)
+		if !isModule {
+			return nil, false
+		}
+		obj, ok = module.Exports[member]
+	}
+
+	return obj, ok
+}
+
+func result(obj object.Object) (interface{}, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: err}
+	}
+	return FromObject(obj), nil
+}
@@ -0,0 +1,382 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{`"a" + "b"`, "ab"},
+		{"1 < 2", true},
+		{"if (false) { 1 }", nil},
+		{"let x = 5;", nil},
+		{"[1, [true, \"x\"]]", []interface{}{int64(1), []interface{}{true, "x"}}},
+		{`{"a": 1}`, map[interface{}]interface{}{"a": int64(1)}},
+		{"9223372036854775807 + 1", new(big.Int).Lsh(big.NewInt(1), 63)},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Eval(tt.input)
+		assert.NoError(err, tt.input)
+		assert.Equal(tt.expected, result, tt.input)
+	}
+}
+
+func TestEvalSelfReferencing(t *testing.T) {
+	assert := assert.New(t)
+
+	result, err := New().Eval("let a = [1]; a[0] = a; a")
+	assert.NoError(err)
+	if array, ok := result.([]interface{}); assert.True(ok, "result is not a slice. got=%T", result) {
+		assert.Equal(reflect.ValueOf(array).Pointer(), reflect.ValueOf(array[0]).Pointer())
+	}
+
+	result, err = New().Eval(`let h = {}; h["self"] = h; h["list"] = [h]; h`)
+	assert.NoError(err)
+	if hash, ok := result.(map[interface{}]interface{}); assert.True(ok, "result is not a map. got=%T", result) {
+		assert.Equal(reflect.ValueOf(hash).Pointer(), reflect.ValueOf(hash["self"]).Pointer())
+		list := hash["list"].([]interface{})
+		assert.Equal(reflect.ValueOf(hash).Pointer(), reflect.ValueOf(list[0]).Pointer())
+	}
+}
+
+func TestEvalKeepsBindings(t *testing.T) {
+	assert := assert.New(t)
+
+	interp := New()
+	_, err := interp.Eval("let double = fn(x) { x * 2 };")
+	assert.NoError(err)
+
+	result, err := interp.Eval("double(21)")
+	assert.NoError(err)
+	assert.Equal(int64(42), result)
+}
+
+func TestEvalErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := New().Eval("let = 5;")
+	var parseErr *ParseError
+	if assert.True(errors.As(err, &parseErr)) {
+		assert.NotEmpty(parseErr.Diagnostics)
+		assert.Contains(err.Error(), "parse error: ")
+	}
+
+	_, err = New().Eval("1 + true")
+	var runtimeErr *RuntimeError
+	if assert.True(errors.As(err, &runtimeErr)) {
+		assert.Equal("type mismatch: INTEGER + BOOLEAN", runtimeErr.Err.Message)
+		assert.Equal("runtime error: 1:3: type mismatch: INTEGER + BOOLEAN", err.Error())
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{7, int64(7)},
+		{uint8(7), int64(7)},
+		{uint64(math.MaxUint64), new(big.Int).SetUint64(math.MaxUint64)},
+		{big.NewInt(7), int64(7)},
+		{1.5, 1.5},
+		{float32(0.5), 0.5},
+		{"str", "str"},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{[2]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]int{"a": 1}, map[interface{}]interface{}{"a": int64(1)}},
+		{&object.Integer{Value: 3}, int64(3)},
+	}
+
+	for _, tt := range tests {
+		interp := New()
+		assert.NoError(interp.Set("v", tt.value))
+
+		value, ok := interp.Get("v")
+		assert.True(ok)
+		assert.Equal(tt.expected, value, "%#v", tt.value)
+
+		result, err := interp.Eval("v")
+		assert.NoError(err)
+		assert.Equal(tt.expected, result, "%#v", tt.value)
+	}
+
+	_, ok := New().Get("missing")
+	assert.False(ok)
+
+	assert.EqualError(New().Set("v", 1i), "cannot convert complex128 to a Monkey value")
+	assert.EqualError(New().Set("v", map[interface{}]int{struct{}{}: 1}),
+		"cannot convert struct {} to a Monkey value")
+
+	// Keys Go can't use in a map stay Monkey objects
+	interp := New()
+	assert.NoError(interp.Set("v", map[[2]int]string{{1, 2}: "a"}))
+	result, err := interp.Eval("v[[1, 2]]")
+	assert.NoError(err)
+	assert.Equal("a", result)
+
+	value, _ := interp.Get("v")
+	for key, value := range value.(map[interface{}]interface{}) {
+		assert.Equal("[1, 2]", key.(*object.Array).Inspect())
+		assert.Equal("a", value)
+	}
+}
+
+func TestCall(t *testing.T) {
+	assert := assert.New(t)
+
+	interp := New()
+	assert.NoError(interp.Set("limit", 10))
+	_, err := interp.Eval("let allowed = fn(n) { n < limit };")
+	assert.NoError(err)
+
+	result, err := interp.Call("allowed", 3)
+	assert.NoError(err)
+	assert.Equal(true, result)
+
+	result, err = interp.Call("len", "four")
+	assert.NoError(err)
+	assert.Equal(int64(4), result)
+
+	_, err = interp.Call("missing")
+	assert.EqualError(err, "function not found: missing")
+
+	_, err = interp.Call("allowed", true)
+	assert.EqualError(err, "runtime error: 1:25: type mismatch: BOOLEAN < INTEGER")
+}
+
+func TestGoFunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	interp := New()
+	assert.NoError(interp.Set("add", func(a, b int) int { return a + b }))
+	assert.NoError(interp.Set("join", func(sep string, parts ...string) string {
+		result := ""
+		for i, p := range parts {
+			if i > 0 {
+				result += sep
+			}
+			result += p
+		}
+		return result
+	}))
+	assert.NoError(interp.Set("sum", func(xs []int64) int64 {
+		var total int64
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}))
+	assert.NoError(interp.Set("divmod", func(a, b int) (int, int, error) {
+		if b == 0 {
+			return 0, 0, fmt.Errorf("division by zero")
+		}
+		return a / b, a % b, nil
+	}))
+	assert.NoError(interp.Set("double", func(x *big.Int) *big.Int { return x.Mul(x, big.NewInt(2)) }))
+	assert.NoError(interp.Set("half", func(x float64) float64 { return x / 2 }))
+	assert.NoError(interp.Set("noop", func() {}))
+	assert.NoError(interp.Set("typeOf", func(obj object.Object) string { return string(obj.Type()) }))
+	assert.NoError(interp.Set("show", func(x interface{}) string { return fmt.Sprintf("%T %v", x, x) }))
+	assert.NoError(interp.Set("showAll", func(xs ...interface{}) string { return fmt.Sprintf("%v", xs) }))
+	assert.NoError(interp.Set("keys", func(m map[string]interface{}) string { return fmt.Sprintf("%v", m) }))
+	assert.NoError(interp.Set("length", func(s *object.String) int { return len(s.Value) }))
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"add(1, 2)", int64(3)},
+		{`join("-")`, ""},
+		{`join("-", "a", "b")`, "a-b"},
+		{"sum([1, 2, 3])", int64(6)},
+		{"divmod(7, 2)", []interface{}{int64(3), int64(1)}},
+		{"double(3)", int64(6)},
+		{"double(9223372036854775807)", new(big.Int).SetUint64(math.MaxUint64 - 1)},
+		{"half(3)", 1.5},
+		{"half(0.5)", 0.25},
+		{"noop()", nil},
+		{"typeOf(fn() {})", "FUNCTION"},
+		{"show(1)", "int64 1"},
+		{`show("a")`, "string a"},
+		{"show([1, true])", "[]interface {} [1 true]"},
+		{"show(if (false) { 1 })", "<nil> <nil>"},
+		{`showAll(1, "a", 2.5)`, "[1 a 2.5]"},
+		{`keys({"a": 1, "b": [2]})`, "map[a:1 b:[2]]"},
+		{`length("abc")`, int64(3)},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Eval(tt.input)
+		assert.NoError(err, tt.input)
+		assert.Equal(tt.expected, result, tt.input)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1)", "wrong number of arguments. got=1, want=2"},
+		{`join()`, "wrong number of arguments. got=0, want at least 1"},
+		{`add(1, "2")`, "argument 1: cannot use STRING as int"},
+		{`add(1, 2.5)`, "argument 1: cannot use FLOAT as int"},
+		{`sum([1, "2"])`, "argument 0: cannot use STRING as int64"},
+		{"divmod(1, 0)", "division by zero"},
+		{"length(1)", "argument 0: cannot use INTEGER as *object.String"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := interp.Eval(tt.input)
+		var runtimeErr *RuntimeError
+		if assert.True(errors.As(err, &runtimeErr), tt.input) {
+			assert.Equal(tt.expected, runtimeErr.Err.Message, tt.input)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	interp := New()
+	assert.NoError(interp.RegisterFunc("math.abs", func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}))
+	assert.NoError(interp.RegisterFunc("math.max", func(first int64, rest ...int64) int64 {
+		for _, n := range rest {
+			first = max(first, n)
+		}
+		return first
+	}))
+	assert.NoError(interp.Register(&object.Builtin{
+		Name:  "len",
+		Arity: 1,
+		Fn: func(args ...object.Object) object.Object {
+			return &object.Integer{Value: -1}
+		},
+	}))
+	assert.EqualError(interp.RegisterFunc("abs", 5), "cannot register int as a function")
+	assert.EqualError(interp.RegisterFunc("math.abs.x", func() {}), "math.abs is not a namespace")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"math.abs(-3)", int64(3)},
+		{"math.max(1, 7, 3)", int64(7)},
+		{"len([1, 2])", int64(-1)},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Eval(tt.input)
+		assert.NoError(err, tt.input)
+		assert.Equal(tt.expected, result, tt.input)
+	}
+
+	_, err := interp.Eval("math.max()")
+	assert.EqualError(err, "runtime error: 1:9: wrong number of arguments. got=0, want at least 1")
+
+	result, err := interp.Call("len", "four")
+	assert.NoError(err)
+	assert.Equal(int64(-1), result)
+
+	result, err = interp.Eval("let math = {}; math")
+	assert.NoError(err)
+	assert.Equal(map[interface{}]interface{}{}, result)
+
+	// Registered builtins are per interpreter
+	result, err = New().Eval("len([1, 2])")
+	assert.NoError(err)
+	assert.Equal(int64(2), result)
+
+	other := New()
+	assert.NoError(other.RegisterFunc("math.abs", func(n int64) int64 { return n }))
+	result, err = other.Call("math.abs", -3)
+	assert.NoError(err)
+	assert.Equal(int64(-3), result)
+
+	_, err = other.Call("math.missing")
+	assert.EqualError(err, "function not found: math.missing")
+}
+
+func TestModules(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	lib, hidden := filepath.Join(dir, "lib.mk"), filepath.Join(dir, "hidden.mk")
+	assert.NoError(os.WriteFile(lib, []byte(`let value = math.abs(-3);`), 0o644))
+	assert.NoError(os.WriteFile(hidden, []byte(`let value = hidden;`), 0o644))
+	src := `(import "` + lib + `").value`
+
+	// Modules see the builtins of the interpreter importing them
+	interp := New()
+	assert.NoError(interp.RegisterFunc("math.abs", func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}))
+	result, err := interp.Eval(src)
+	assert.NoError(err)
+	assert.Equal(int64(3), result)
+
+	// but not its bindings
+	_, err = interp.Eval(`let hidden = 1; import "` + hidden + `"`)
+	assert.EqualError(err, "runtime error: "+hidden+":1:13: identifier not found: hidden")
+
+	// Each interpreter has its own modules
+	other := New()
+	assert.NoError(other.RegisterFunc("math.abs", func(n int64) int64 { return n }))
+	result, err = other.Eval(src)
+	assert.NoError(err)
+	assert.Equal(int64(-3), result)
+
+	result, err = interp.Eval(src)
+	assert.NoError(err)
+	assert.Equal(int64(3), result)
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	src := "let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };"
+
+	limited := New(WithMaxCallDepth(20))
+	_, err := limited.Eval(src)
+	assert.NoError(err)
+	result, err := limited.Call("count", 19)
+	assert.NoError(err)
+	assert.Equal(int64(19), result)
+	_, err = limited.Eval("count(20)")
+	assert.EqualError(err, "runtime error: 1:55: maximum recursion depth exceeded")
+
+	// Other interpreters keep the default
+	interp := New()
+	_, err = interp.Eval(src)
+	assert.NoError(err)
+	result, err = interp.Call("count", 100)
+	assert.NoError(err)
+	assert.Equal(int64(100), result)
+}
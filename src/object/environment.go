@@ -3,19 +3,87 @@ package object
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+	// Number of function calls active in this environment
+	callDepth int
+	// Limit of callDepth, 0 for the default of the evaluator
+	maxCallDepth int
+	// Shared by all the environments created from the same NewEnvironment
+	modules *Modules
+	// The import being evaluated in this environment, if any
+	moduleLoad *ModuleLoad
 }
 
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	return &Environment{store: s, modules: &Modules{}}
 }
 
 func NewEnclosingEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.callDepth = outer.callDepth
+	env.maxCallDepth = outer.maxCallDepth
+	env.modules = outer.modules
+	env.moduleLoad = outer.moduleLoad
 	return env
 }
 
+// NewModuleEnvironment creates the environment of load, a file imported from
+// importer. It shares the module cache of importer but none of its bindings,
+// except those of the environment shared with modules, if any.
+func NewModuleEnvironment(importer *Environment, load *ModuleLoad) *Environment {
+	env := NewEnvironment()
+	if scope := importer.modules.scope; scope != nil {
+		env = NewEnclosingEnvironment(scope)
+	}
+	env.maxCallDepth = importer.maxCallDepth
+	env.modules = importer.modules
+	env.moduleLoad = load
+	return env
+}
+
+// ShareWithModules makes the bindings of e, such as the builtins of an
+// interpreter, visible to the modules imported from environments sharing its
+// module cache.
+func (e *Environment) ShareWithModules() {
+	e.modules.scope = e
+}
+
+// NewCallEnvironment creates the environment of a function call made
+// callDepth calls deep, enclosed by the environment the function closes over.
+func NewCallEnvironment(outer *Environment, callDepth int) *Environment {
+	env := NewEnclosingEnvironment(outer)
+	env.callDepth = callDepth
+	return env
+}
+
+func (e *Environment) CallDepth() int {
+	return e.callDepth
+}
+
+// MaxCallDepth returns how many function calls can be active at once in this
+// environment, or 0 if the evaluator's default applies.
+func (e *Environment) MaxCallDepth() int {
+	return e.maxCallDepth
+}
+
+// SetMaxCallDepth limits the calls active at once in e and the environments
+// created from it afterwards, 0 restoring the default.
+func (e *Environment) SetMaxCallDepth(depth int) {
+	e.maxCallDepth = depth
+}
+
+// Modules returns the cache of the modules imported from this environment.
+func (e *Environment) Modules() *Modules {
+	return e.modules
+}
+
+// ModuleLoad returns the import being evaluated in this environment, or nil
+// outside imported files.
+func (e *Environment) ModuleLoad() *ModuleLoad {
+	return e.moduleLoad
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -24,7 +92,37 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// GetLocal returns the binding of name in this scope, ignoring the ones of
+// enclosing scopes.
+func (e *Environment) GetLocal(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	return obj, ok
+}
+
 func (e *Environment) Set(name string, obj Object) Object {
 	e.store[name] = obj
 	return obj
 }
+
+// Assign updates the binding of name in the nearest scope that defines it,
+// reporting false if none does.
+func (e *Environment) Assign(name string, obj Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = obj
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, obj)
+	}
+	return false
+}
+
+// Bindings returns a copy of the bindings defined directly in this scope,
+// ignoring the ones of enclosing scopes.
+func (e *Environment) Bindings() map[string]Object {
+	bindings := make(map[string]Object, len(e.store))
+	for name, obj := range e.store {
+		bindings[name] = obj
+	}
+	return bindings
+}
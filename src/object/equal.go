@@ -0,0 +1,113 @@
+package object
+
+import "math/big"
+
+// Equal reports whether a and b are structurally equal. Numbers are equal if
+// they have the same value, whatever their representation, strings, booleans
+// and nulls by value, and arrays and hashes if their elements or pairs are
+// equal. Values containing themselves are handled. Any other object is only
+// equal to itself.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+func equal(a, b Object, visiting map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Integer, *BigInteger, *Float:
+		return equalNumbers(a, b)
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		// A pair already being compared further up is assumed equal, any
+		// difference is found by the comparison in progress
+		pair := [2]Object{a, b}
+		if visiting[pair] {
+			return true
+		}
+		visiting[pair] = true
+		defer delete(visiting, pair)
+
+		for i, el := range a.Elements {
+			if !equal(el, b.Elements[i], visiting) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+
+		pair := [2]Object{a, b}
+		if visiting[pair] {
+			return true
+		}
+		visiting[pair] = true
+		defer delete(visiting, pair)
+
+		for _, aPair := range a.Pairs() {
+			bValue, ok := b.Get(aPair.Key)
+			if !ok || !equal(aPair.Value, bValue, visiting) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+func equalNumbers(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *BigInteger:
+			return b.Value.Cmp(big.NewInt(a.Value)) == 0
+		case *Float:
+			return float64(a.Value) == b.Value
+		}
+	case *BigInteger:
+		switch b := b.(type) {
+		case *Integer:
+			return equalNumbers(b, a)
+		case *BigInteger:
+			return a.Value.Cmp(b.Value) == 0
+		case *Float:
+			f, _ := new(big.Float).SetInt(a.Value).Float64()
+			return f == b.Value
+		}
+	case *Float:
+		switch b := b.(type) {
+		case *Integer, *BigInteger:
+			return equalNumbers(b, a)
+		case *Float:
+			return a.Value == b.Value
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package object
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	assert := assert.New(t)
+
+	one := &Integer{Value: 1}
+	hash := func(pairs ...Object) *Hash {
+		h := &Hash{}
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i], pairs[i+1])
+		}
+		return h
+	}
+	array := func(elements ...Object) *Array {
+		return &Array{Elements: elements}
+	}
+	huge, _ := new(big.Int).SetString("99999999999999999999", 10)
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{one, &Integer{Value: 1}, true},
+		{one, &Integer{Value: 2}, false},
+		{one, &Float{Value: 1}, true},
+		{one, &BigInteger{Value: big.NewInt(1)}, true},
+		{&BigInteger{Value: huge}, &BigInteger{Value: new(big.Int).Set(huge)}, true},
+		{&BigInteger{Value: huge}, one, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "a"}, &String{Value: "b"}, false},
+		{&String{Value: "1"}, one, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Null{}, &Null{}, true},
+		{&Null{}, &Boolean{Value: false}, false},
+		{array(), array(), true},
+		{array(one, array(one)), array(one, array(&Integer{Value: 1})), true},
+		{array(one), array(one, one), false},
+		{array(one), hash(), false},
+		{hash(), hash(), true},
+		{hash(&String{Value: "a"}, array(one)), hash(&String{Value: "a"}, array(one)), true},
+		{hash(&String{Value: "a"}, one), hash(&String{Value: "a"}, &Integer{Value: 2}), false},
+		{hash(&String{Value: "a"}, one), hash(&String{Value: "b"}, one), false},
+		{&Builtin{}, &Builtin{}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.expected, Equal(tt.a, tt.b), "%s == %s", tt.a.Inspect(), tt.b.Inspect())
+		assert.Equal(tt.expected, Equal(tt.b, tt.a), "%s == %s", tt.b.Inspect(), tt.a.Inspect())
+	}
+}
+
+func TestEqualCycles(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &Array{}
+	a.Elements = []Object{&Integer{Value: 1}, a}
+	b := &Array{}
+	b.Elements = []Object{&Integer{Value: 1}, b}
+	c := &Array{}
+	c.Elements = []Object{&Integer{Value: 2}, c}
+
+	assert.True(Equal(a, a))
+	assert.True(Equal(a, b))
+	assert.False(Equal(a, c))
+
+	key := &String{Value: "self"}
+	h1 := &Hash{}
+	h1.Set(key, h1)
+	h2 := &Hash{}
+	h2.Set(key, h2)
+
+	assert.True(Equal(h1, h2))
+}
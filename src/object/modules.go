@@ -0,0 +1,104 @@
+package object
+
+import "sync"
+
+// Modules caches the modules imported from a tree of environments, so every
+// file is evaluated once. Imports of a file that is still being evaluated
+// wait for it to finish.
+type Modules struct {
+	mu     sync.Mutex
+	loaded map[string]*Module
+	loads  map[string]*ModuleLoad
+	// The environment modules are evaluated in, see ShareWithModules
+	scope *Environment
+}
+
+// ModuleLoad is the evaluation of an imported file. Parent is the load of the
+// file that imported it, nil when the import came from outside any module.
+type ModuleLoad struct {
+	Path   string
+	Parent *ModuleLoad
+
+	done   chan struct{}
+	result Object
+	// The load the evaluation of this one is waiting for
+	waitsOn *ModuleLoad
+}
+
+// Import returns the module at path, calling load to evaluate it unless it
+// was loaded already. importer is the load the import happens in. If path is
+// being loaded elsewhere, Import waits for that load and returns its result,
+// unless the wait would never end, in which case it returns the files of the
+// import cycle instead.
+func (m *Modules) Import(path string, importer *ModuleLoad, load func(*ModuleLoad) Object) (Object, []string) {
+	m.mu.Lock()
+	if module, ok := m.loaded[path]; ok {
+		m.mu.Unlock()
+		return module, nil
+	}
+
+	if pending, ok := m.loads[path]; ok {
+		if cycle := importer.cycleTo(pending); cycle != nil {
+			m.mu.Unlock()
+			return nil, cycle
+		}
+		importer.setWaitsOn(pending)
+		m.mu.Unlock()
+
+		<-pending.done
+
+		m.mu.Lock()
+		importer.setWaitsOn(nil)
+		m.mu.Unlock()
+		return pending.result, nil
+	}
+
+	current := &ModuleLoad{Path: path, Parent: importer, done: make(chan struct{})}
+	if m.loads == nil {
+		m.loads = map[string]*ModuleLoad{}
+	}
+	m.loads[path] = current
+	m.mu.Unlock()
+
+	result := load(current)
+
+	m.mu.Lock()
+	delete(m.loads, path)
+	if module, ok := result.(*Module); ok {
+		if m.loaded == nil {
+			m.loaded = map[string]*Module{}
+		}
+		m.loaded[path] = module
+	}
+	current.result = result
+	close(current.done)
+	m.mu.Unlock()
+
+	return result, nil
+}
+
+// cycleTo returns the paths of the import cycle that waiting for target from
+// l would close, or nil if target finishes without l.
+func (l *ModuleLoad) cycleTo(target *ModuleLoad) []string {
+	var waits []string
+	for t := target; t != nil; t = t.waitsOn {
+		waits = append(waits, t.Path)
+
+		var chain []string
+		for load := l; load != nil; load = load.Parent {
+			chain = append([]string{load.Path}, chain...)
+			if load == t {
+				return append(chain, waits...)
+			}
+		}
+	}
+	return nil
+}
+
+// setWaitsOn records that l, and so the loads that imported it, wait for
+// target.
+func (l *ModuleLoad) setWaitsOn(target *ModuleLoad) {
+	for load := l; load != nil; load = load.Parent {
+		load.waitsOn = target
+	}
+}
@@ -2,9 +2,17 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"hash/fnv"
+	"math"
+	"math/big"
 	"monkey/ast"
+	"monkey/code"
+	"monkey/token"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -14,8 +22,12 @@ type BuiltinFunction func(args ...Object) Object
 
 const (
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
 	FUNCTION_OBJ     = "FUNCTION"
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	BUILTIN_OBJ      = "BUILTIN"
 	STRING_OBJ       = "STRING"
@@ -23,6 +35,9 @@ const (
 	ERROR_OBJ        = "ERROR"
 	HASH_OBJ         = "HASH"
 	NULL_OBJ         = "NULL"
+	MODULE_OBJ       = "MODULE"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 )
 
 type Object interface {
@@ -31,7 +46,11 @@ type Object interface {
 }
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string
+	// Number of arguments Fn takes, or the minimum number if Variadic
+	Arity    int
+	Variadic bool
+	Fn       BuiltinFunction
 }
 
 type HashKey struct {
@@ -43,6 +62,68 @@ type HashTable interface {
 	HashKey() HashKey
 }
 
+// Hashable reports whether obj can be used as a hash key. Arrays and hashes
+// can only if all the values they contain can and they don't contain
+// themselves.
+func Hashable(obj Object) bool {
+	return hashable(obj, map[Object]bool{})
+}
+
+func hashable(obj Object, visiting map[Object]bool) bool {
+	switch obj := obj.(type) {
+	case *Array:
+		if visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		for _, el := range obj.Elements {
+			if !hashable(el, visiting) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		if visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		for _, pair := range obj.Pairs() {
+			if !hashable(pair.Key, visiting) || !hashable(pair.Value, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		_, ok := obj.(HashTable)
+		return ok
+	}
+}
+
+// frozenKey copies array and hash keys, so changing them after they were used
+// as a key can't corrupt the hash.
+func frozenKey(key Object) Object {
+	switch key := key.(type) {
+	case *Array:
+		elements := make([]Object, len(key.Elements))
+		for i, el := range key.Elements {
+			elements[i] = frozenKey(el)
+		}
+		return &Array{Elements: elements}
+	case *Hash:
+		frozen := &Hash{}
+		for _, pair := range key.Pairs() {
+			frozen.Set(pair.Key, frozenKey(pair.Value))
+		}
+		return frozen
+	default:
+		return key
+	}
+}
+
 func (b *Builtin) Type() ObjectType {
 	return BUILTIN_OBJ
 }
@@ -50,6 +131,17 @@ func (b *Builtin) Inspect() string {
 	return "builtin function"
 }
 
+// Call calls Fn, or returns an error if args does not match the arity of b.
+func (b *Builtin) Call(args ...Object) Object {
+	switch {
+	case b.Variadic && len(args) < b.Arity:
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want at least %d", len(args), b.Arity)}
+	case !b.Variadic && len(args) != b.Arity:
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), b.Arity)}
+	}
+	return b.Fn(args...)
+}
+
 type Integer struct {
 	Value int64
 }
@@ -61,6 +153,47 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+// BigInteger is an integer that doesn't fit in 64 bits. It has the same type
+// as Integer, code creating integers from big values uses IntegerFromBig so
+// each value has a single representation.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType {
+	return INTEGER_OBJ
+}
+func (bi *BigInteger) Inspect() string {
+	return bi.Value.String()
+}
+
+// IntegerFromBig returns value as an Integer if it fits in 64 bits, or as a
+// BigInteger otherwise.
+func IntegerFromBig(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInteger{Value: value}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// Inspect formats f in the shortest form that parses back to the same value,
+// always with a fraction or exponent so it still reads as a float.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -91,11 +224,38 @@ func (a *Array) Type() ObjectType {
 	return ARRAY_OBJ
 }
 func (a *Array) Inspect() string {
+	return inspect(a, map[Object]bool{})
+}
+
+// inspect formats obj, showing arrays and hashes that contain themselves as
+// [...] and {...} where they repeat.
+func inspect(obj Object, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if visiting[obj] {
+			return "[...]"
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+		return obj.inspect(visiting)
+	case *Hash:
+		if visiting[obj] {
+			return "{...}"
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+		return obj.inspect(visiting)
+	default:
+		return obj.Inspect()
+	}
+}
+
+func (a *Array) inspect(visiting map[Object]bool) string {
 	var out bytes.Buffer
 
 	elements := []string{}
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, visiting))
 	}
 
 	out.WriteString("[")
@@ -110,8 +270,82 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash maps keys to values, keeping them in insertion order. Keys are bucketed
+// by their HashKey and compared with Equal, so keys whose hash keys collide
+// don't overwrite each other. The zero value is an empty hash.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs   []HashPair
+	buckets map[HashKey][]int // Indexes into pairs
+	hasher  func(HashTable) HashKey
+}
+
+// NewHashWithHasher returns an empty hash that buckets its keys with hasher
+// instead of their HashKey method.
+func NewHashWithHasher(hasher func(HashTable) HashKey) *Hash {
+	return &Hash{hasher: hasher}
+}
+
+func (h *Hash) hashKey(key HashTable) HashKey {
+	if h.hasher != nil {
+		return h.hasher(key)
+	}
+	return key.HashKey()
+}
+
+// Get returns the value bound to a key equal to key.
+func (h *Hash) Get(key Object) (Object, bool) {
+	hashable, ok := key.(HashTable)
+	if !ok {
+		return nil, false
+	}
+
+	for _, i := range h.buckets[h.hashKey(hashable)] {
+		if Equal(h.pairs[i].Key, key) {
+			return h.pairs[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set binds key to value. An equal key keeps its place and gets the new
+// value, other keys are added last. key must be Hashable, arrays and hashes
+// are copied so later changes to them don't affect h.
+func (h *Hash) Set(key, value Object) {
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+
+	hashKey := h.hashKey(key.(HashTable))
+	for _, i := range h.buckets[hashKey] {
+		if Equal(h.pairs[i].Key, key) {
+			h.pairs[i].Value = value
+			return
+		}
+	}
+
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: frozenKey(key), Value: value})
+}
+
+// Len returns the number of pairs in h.
+func (h *Hash) Len() int {
+	return len(h.pairs)
+}
+
+// Keys returns the keys of h in insertion order. Array and hash keys are
+// copies, so changing them doesn't affect h.
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, len(h.pairs))
+	for i, pair := range h.pairs {
+		keys[i] = frozenKey(pair.Key)
+	}
+	return keys
+}
+
+// Pairs returns the pairs of h in insertion order. The slice must not be
+// modified.
+func (h *Hash) Pairs() []HashPair {
+	return h.pairs
 }
 
 func (h *Hash) Type() ObjectType {
@@ -119,12 +353,16 @@ func (h *Hash) Type() ObjectType {
 }
 
 func (h *Hash) Inspect() string {
+	return inspect(h, map[Object]bool{})
+}
+
+func (h *Hash) inspect(visiting map[Object]bool) string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
+			inspect(pair.Key, visiting), inspect(pair.Value, visiting)))
 	}
 
 	out.WriteString("{")
@@ -134,6 +372,29 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Module is an imported file, its exports being the top-level bindings of the
+// environment it was evaluated in, or a namespace of builtins.
+type Module struct {
+	Path    string
+	Exports map[string]Object
+	// Set for the namespaces of builtins, which are created by defining the
+	// builtins in them
+	Namespace bool
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+func (m *Module) Inspect() string {
+	names := make([]string, 0, len(m.Exports))
+	for name := range m.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Sprintf("module(%s){%s}", m.Path, strings.Join(names, ", "))
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType {
@@ -154,21 +415,107 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// Break and Continue are the signals of `break` and `continue`. Like
+// ReturnValue, blocks stop at them and pass them up to the enclosing loop.
+type Break struct{}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// TailCall is a call in tail position that was evaluated up to its arguments.
+// It is passed up to the call of the enclosing function, which makes it in
+// its place so tail recursion doesn't grow the Go stack.
+type TailCall struct {
+	Function *Function
+	Args     []Object
+	CallSite token.Position
+}
+
+func (tc *TailCall) Type() ObjectType {
+	return TAIL_CALL_OBJ
+}
+func (tc *TailCall) Inspect() string {
+	return "tail call"
+}
+
 type Error struct {
 	Message string
+	Pos     token.Position // Where the error was raised, if known
+	Stack   []StackFrame   // Calls the error unwound through, innermost first
+	Value   Object         // What a `throw` threw, nil for interpreter errors
+}
+
+// StackFrame is a function call that was active when an error was raised.
+type StackFrame struct {
+	Function string         // Empty for anonymous functions
+	Pos      token.Position // The call site
+}
+
+func (sf StackFrame) String() string {
+	name := sf.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("at %s (%s)", name, sf.Pos)
 }
 
 func (e *Error) Type() ObjectType {
 	return ERROR_OBJ
 }
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
 	return "ERROR: " + e.Message
 }
 
+// Frames repeated in a row more than this, as in runaway recursion, are
+// summarised in stack traces
+const maxRepeatedFrames = 3
+
+// StackTrace renders the call stack, one frame per line.
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	for i := 0; i < len(e.Stack); {
+		repeats := 1
+		for i+repeats < len(e.Stack) && e.Stack[i+repeats] == e.Stack[i] {
+			repeats++
+		}
+
+		for range min(repeats, maxRepeatedFrames) {
+			out.WriteString("\t" + e.Stack[i].String() + "\n")
+		}
+		if repeats > maxRepeatedFrames {
+			fmt.Fprintf(&out, "\t... repeated %d more times\n", repeats-maxRepeatedFrames)
+		}
+
+		i += repeats
+	}
+
+	return out.String()
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // See ast.FunctionLiteral
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string // Name of the `let` binding it was defined in, if any
 }
 
 func (f *Function) Type() ObjectType {
@@ -177,14 +524,9 @@ func (f *Function) Type() ObjectType {
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParameterList(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -208,8 +550,95 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (bi *BigInteger) HashKey() HashKey {
+	if bi.Value.IsInt64() {
+		return (&Integer{Value: bi.Value.Int64()}).HashKey()
+	}
+
+	h := fnv.New64a()
+	if bi.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(bi.Value.Bytes())
+	return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
+
+func (f *Float) HashKey() HashKey {
+	// Whole floats are equal to the integer of the same value, so they must
+	// be the same key. This also merges -0.0 and 0.0
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return (&Integer{Value: int64(f.Value)}).HashKey()
+	}
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		value, _ := big.NewFloat(f.Value).Int(nil)
+		return (&BigInteger{Value: value}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
+
+// HashKey combines the keys of the elements, so equal arrays have equal keys.
+// Elements that aren't hashable only contribute their type, see Hashable.
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	for _, el := range a.Elements {
+		writeHashKey(h, el)
+	}
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+// HashKey combines the keys of the pairs independently of their order.
+func (h *Hash) HashKey() HashKey {
+	var value uint64
+	for _, pair := range h.Pairs() {
+		pairHash := fnv.New64a()
+		writeHashKey(pairHash, pair.Key)
+		writeHashKey(pairHash, pair.Value)
+		value += pairHash.Sum64()
+	}
+	return HashKey{Type: h.Type(), Value: value}
+}
+
+func writeHashKey(h hash.Hash64, obj Object) {
+	key := HashKey{Type: obj.Type()}
+	if hashable, ok := obj.(HashTable); ok {
+		key = hashable.HashKey()
+	}
+
+	h.Write([]byte(key.Type))
+	binary.Write(h, binary.LittleEndian, key.Value)
+}
+
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	Name          string
+	LocalNames    []string // Used by the VM to report unset locals by name
+}
+
+func (cf *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+// Closures are what the VM calls functions, so they report the same type the
+// evaluator uses for them
+func (c *Closure) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
@@ -1,6 +1,10 @@
 package object
 
 import (
+	"math"
+	"math/big"
+	"monkey/token"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +45,239 @@ func TestBooleanHashKey(t *testing.T) {
 	assert.Equal(diff1.HashKey(), diff2.HashKey())
 	assert.NotEqual(same1.HashKey(), diff2.HashKey())
 }
+
+func TestBigIntegerHashKey(t *testing.T) {
+	assert := assert.New(t)
+	big1, _ := new(big.Int).SetString("99999999999999999999", 10)
+	big2, _ := new(big.Int).SetString("99999999999999999999", 10)
+	same1 := &BigInteger{Value: big1}
+	same2 := &BigInteger{Value: big2}
+	diff := &BigInteger{Value: new(big.Int).Neg(big1)}
+
+	assert.Equal(same1.HashKey(), same2.HashKey())
+	assert.NotEqual(same1.HashKey(), diff.HashKey())
+	assert.Equal((&Integer{Value: 5}).HashKey(), (&BigInteger{Value: big.NewInt(5)}).HashKey())
+}
+
+func TestIntegerFromBig(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(&Integer{Value: -5}, IntegerFromBig(big.NewInt(-5)))
+
+	value, _ := new(big.Int).SetString("-99999999999999999999", 10)
+	assert.Equal(&BigInteger{Value: value}, IntegerFromBig(value))
+}
+
+func TestFloatHashKey(t *testing.T) {
+	assert := assert.New(t)
+	same1 := &Float{Value: 1.5}
+	same2 := &Float{Value: 1.5}
+	diff1 := &Float{Value: 2.5}
+	diff2 := &Float{Value: 2.5}
+
+	assert.Equal(same1.HashKey(), same2.HashKey())
+	assert.Equal(diff1.HashKey(), diff2.HashKey())
+	assert.NotEqual(same1.HashKey(), diff2.HashKey())
+	assert.Equal((&Float{Value: 0}).HashKey(), (&Float{Value: math.Copysign(0, -1)}).HashKey())
+	assert.Equal((&Integer{Value: 2}).HashKey(), (&Float{Value: 2}).HashKey())
+
+	// Also beyond the range of int64
+	for _, value := range []float64{1e19, -1e19, 1e300} {
+		integer, _ := big.NewFloat(value).Int(nil)
+		assert.Equal((&BigInteger{Value: integer}).HashKey(), (&Float{Value: value}).HashKey())
+	}
+	assert.NotEqual((&Float{Value: math.Inf(1)}).HashKey(), (&Float{Value: math.Inf(-1)}).HashKey())
+}
+
+func TestFloatInspect(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{1e21, "1e+21"},
+		{1.5e-7, "1.5e-07"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		inspected := (&Float{Value: tt.value}).Inspect()
+		assert.Equal(tt.expected, inspected)
+
+		// Printed floats parse back to the same value
+		if !math.IsInf(tt.value, 0) {
+			parsed, err := strconv.ParseFloat(inspected, 64)
+			assert.NoError(err)
+			assert.Equal(tt.value, parsed)
+		}
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	assert := assert.New(t)
+	same1 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	same2 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	diff1 := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+	diff2 := &Array{Elements: []Object{&Integer{Value: 1}}}
+	nested := &Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 1}}}}}
+
+	assert.Equal(same1.HashKey(), same2.HashKey())
+	assert.NotEqual(same1.HashKey(), diff1.HashKey())
+	assert.NotEqual(same1.HashKey(), diff2.HashKey())
+	assert.NotEqual(diff2.HashKey(), nested.HashKey())
+	assert.Equal(diff2.HashKey(), (&Array{Elements: []Object{&Float{Value: 1}}}).HashKey())
+}
+
+func TestHashHashKey(t *testing.T) {
+	assert := assert.New(t)
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	same1 := &Hash{}
+	same1.Set(a, one)
+	same1.Set(b, two)
+	same2 := &Hash{}
+	same2.Set(b, two)
+	same2.Set(a, one)
+	swapped := &Hash{}
+	swapped.Set(a, two)
+	swapped.Set(b, one)
+
+	assert.Equal(same1.HashKey(), same2.HashKey())
+	assert.NotEqual(same1.HashKey(), swapped.HashKey())
+}
+
+func TestHashable(t *testing.T) {
+	assert := assert.New(t)
+	fn := &Builtin{}
+
+	assert.True(Hashable(&Integer{Value: 1}))
+	assert.True(Hashable(&Array{Elements: []Object{&Array{}, &String{}}}))
+	assert.False(Hashable(fn))
+	assert.False(Hashable(&Null{}))
+	assert.False(Hashable(&Array{Elements: []Object{&Array{Elements: []Object{fn}}}}))
+
+	hash := &Hash{}
+	hash.Set(&String{Value: "f"}, fn)
+	assert.False(Hashable(hash))
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	hash := &Hash{}
+	for _, key := range []string{"c", "a", "b"} {
+		hash.Set(&String{Value: key}, &Integer{Value: int64(len(key))})
+	}
+	hash.Set(&String{Value: "a"}, &Integer{Value: 5})
+
+	assert.Equal("{c: 1, a: 5, b: 1}", hash.Inspect())
+	assert.Equal("c", hash.Pairs()[0].Key.Inspect())
+}
+
+func TestHashCollisions(t *testing.T) {
+	assert := assert.New(t)
+
+	hash := NewHashWithHasher(func(HashTable) HashKey { return HashKey{} })
+	a, b := &String{Value: "a"}, &Integer{Value: 1}
+
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	assert.Equal(2, hash.Len())
+
+	value, ok := hash.Get(&String{Value: "a"})
+	assert.True(ok)
+	assert.Equal(int64(1), value.(*Integer).Value)
+
+	value, ok = hash.Get(&Integer{Value: 1})
+	assert.True(ok)
+	assert.Equal(int64(2), value.(*Integer).Value)
+
+	_, ok = hash.Get(&String{Value: "c"})
+	assert.False(ok)
+
+	// Setting an equal key replaces its value without adding a pair
+	hash.Set(&Float{Value: 1}, &Integer{Value: 3})
+	assert.Equal(2, hash.Len())
+	value, _ = hash.Get(b)
+	assert.Equal(int64(3), value.(*Integer).Value)
+	value, _ = hash.Get(a)
+	assert.Equal(int64(1), value.(*Integer).Value)
+}
+
+func TestHashSetCopiesKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	key := &Array{Elements: []Object{&Integer{Value: 1}}}
+	hash := &Hash{}
+	hash.Set(key, &Boolean{Value: true})
+	key.Elements[0] = &Integer{Value: 2}
+
+	_, ok := hash.Get(&Array{Elements: []Object{&Integer{Value: 1}}})
+	assert.True(ok)
+	_, ok = hash.Get(key)
+	assert.False(ok)
+
+	// Nor does changing the keys it hands out
+	hash.Keys()[0].(*Array).Elements[0] = &Integer{Value: 3}
+	assert.Equal("{[1]: true}", hash.Inspect())
+}
+
+func TestSelfReferencingObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	array.Elements = append(array.Elements, array)
+	hash := &Hash{}
+	hash.Set(&String{Value: "self"}, hash)
+	hash.Set(&String{Value: "array"}, array)
+
+	assert.Equal("[1, [...]]", array.Inspect())
+	assert.Equal("{self: {...}, array: [1, [...]]}", hash.Inspect())
+	assert.False(Hashable(array))
+	assert.False(Hashable(hash))
+
+	// Repeating without a cycle is fine
+	shared := &Array{}
+	assert.Equal("[[], []]", (&Array{Elements: []Object{shared, shared}}).Inspect())
+	assert.True(Hashable(&Array{Elements: []Object{shared, shared}}))
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	assert := assert.New(t)
+
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosingEnvironment(outer)
+
+	assert.True(inner.Assign("x", &Integer{Value: 2}))
+	assert.Empty(inner.Bindings())
+	x, _ := outer.Get("x")
+	assert.Equal(int64(2), x.(*Integer).Value)
+
+	assert.False(inner.Assign("y", &Integer{Value: 3}))
+	_, ok := inner.Get("y")
+	assert.False(ok)
+}
+
+func TestStackTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	recursive := StackFrame{Function: "f", Pos: token.Position{Line: 1, Column: 20}}
+	err := &Error{Stack: []StackFrame{
+		{Pos: token.Position{Line: 1, Column: 5}},
+		recursive, recursive, recursive, recursive, recursive,
+		{Function: "f", Pos: token.Position{Line: 2, Column: 1}},
+	}}
+
+	assert.Equal("\tat <anonymous> (1:5)\n"+
+		"\tat f (1:20)\n"+
+		"\tat f (1:20)\n"+
+		"\tat f (1:20)\n"+
+		"\t... repeated 2 more times\n"+
+		"\tat f (2:1)\n", err.StackTrace())
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// DiagnosticCode identifies the kind of problem, so tools can match on it
+// instead of on the message text.
+type DiagnosticCode string
+
+const (
+	CodeUnexpectedToken DiagnosticCode = "P001"
+	CodeNoPrefixParseFn DiagnosticCode = "P002"
+	CodeInvalidInteger  DiagnosticCode = "P003"
+	CodeInvalidFloat    DiagnosticCode = "P004"
+
+	CodeInvalidAssignmentTarget DiagnosticCode = "P005"
+	CodeOutsideLoop             DiagnosticCode = "P006"
+	CodeInvalidParameter        DiagnosticCode = "P007"
+)
+
+// Span is the source range a diagnostic refers to. End is exclusive.
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     DiagnosticCode
+	Message  string
+	Span     Span
+	Expected []token.TokenType // Token types that would have been accepted
+	Actual   token.TokenType   // Type of the offending token
+	Hint     string            // Optional suggestion on how to fix it
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
+
+// tokenSpan returns the span covered by tok in the source.
+func tokenSpan(tok token.Token) Span {
+	width := len(tok.Literal)
+	if tok.Type == token.STRING {
+		// The literal doesn't include the quotes
+		width += 2
+	}
+
+	end := tok.Pos
+	end.Column += width
+	return Span{Start: tok.Pos, End: end}
+}
+
+var closingDelimiters = map[token.TokenType]string{
+	token.RPAREN:   ")",
+	token.RBRACE:   "}",
+	token.RBRACKET: "]",
+}
+
+func unexpectedTokenHint(expected token.TokenType, actual token.Token) string {
+	if delimiter, ok := closingDelimiters[expected]; ok {
+		return fmt.Sprintf("add the missing %q", delimiter)
+	}
+
+	switch expected {
+	case token.IDENTIFIER:
+		if token.LookupIdentifier(actual.Literal) != token.IDENTIFIER {
+			return fmt.Sprintf("%q is a keyword and can't be used as a name", actual.Literal)
+		}
+		return "a name was expected here"
+	case token.ASSIGN:
+		return "bindings take the form `let <name> = <value>;`"
+	}
+
+	return ""
+}
@@ -6,6 +6,7 @@ import (
 
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -156,6 +157,196 @@ func TestIfElseExpression(t *testing.T) {
 	testIdentifier(assert, alt.Expression, "y")
 }
 
+func TestThrowStatement(t *testing.T) {
+	assert := assert.New(t)
+	input := `throw "boom";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assert.Equal(1, len(program.Statements))
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	assert.True(ok, "program.Statements[0] is not ast.ThrowStatement. got=%T", program.Statements[0])
+	testStringLiteral(assert, stmt.Value, "boom")
+}
+
+func TestTryExpression(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input      string
+		catchParam string
+		hasCatch   bool
+		hasFinally bool
+		expected   string
+	}{
+		{"try { x } catch (e) { y }", "e", true, false, "try x catch (e) y"},
+		{"try { x } finally { z }", "", false, true, "try x finally z"},
+		{"try { x } catch (err) { y } finally { z }", "err", true, true, "try x catch (err) y finally z"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		assert.Equal(1, len(program.Statements))
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		assert.True(ok, "stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+
+		testIdentifier(assert, exp.Block.Statements[0].(*ast.ExpressionStatement).Expression, "x")
+		assert.Equal(tt.hasCatch, exp.Catch != nil)
+		assert.Equal(tt.hasFinally, exp.Finally != nil)
+		if tt.hasCatch {
+			testIdentifier(assert, exp.CatchParam, tt.catchParam)
+		}
+		assert.Equal(tt.expected, program.String())
+	}
+}
+
+func TestTryWithoutHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lexer.New("try { x }; 1")
+	p := New(l)
+	p.ParseProgram()
+
+	if assert.Equal(1, len(p.Errors())) {
+		assert.Equal("1:10: expected next token to be CATCH or FINALLY, got SEMICOLON instead",
+			p.Errors()[0].String())
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lexer.New(`while (x < 10) { x = x + 1; continue; };`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if !assert.Len(program.Statements, 1) {
+		return
+	}
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if assert.True(ok, "stmt is not ast.WhileStatement. got=%T", program.Statements[0]) {
+		testInfixExpression(assert, stmt.Condition, "x", "<", 10)
+		if assert.Len(stmt.Body.Statements, 2) {
+			_, ok = stmt.Body.Statements[1].(*ast.ContinueStatement)
+			assert.True(ok)
+		}
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lexer.New(`for (x in [1, 2]) { if (x > 1) { break } }`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if !assert.Len(program.Statements, 1) {
+		return
+	}
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if assert.True(ok, "stmt is not ast.ForStatement. got=%T", program.Statements[0]) {
+		testIdentifier(assert, stmt.Variable, "x")
+		assert.Equal("[1, 2]", stmt.Iterable.String())
+		assert.Equal("for (x in [1, 2]) if(x > 1) break;", stmt.String())
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"1:1: break outside loop"}},
+		{"if (true) { continue }", []string{"1:13: continue outside loop"}},
+		{"while (true) { fn() { break } }", []string{"1:23: break outside loop"}},
+		{"while (true) { fn() { while (true) { break } }; break }", []string{}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := []string{}
+		for _, d := range p.Errors() {
+			assert.Equal(CodeOutsideLoop, d.Code)
+			errors = append(errors, d.String())
+		}
+		assert.Equal(tt.expected, errors, tt.input)
+	}
+}
+
+func TestImportExpression(t *testing.T) {
+	assert := assert.New(t)
+	input := `let lib = import "lib.mk";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	exp, ok := stmt.Value.(*ast.ImportExpression)
+	assert.True(ok, "stmt.Value is not ast.ImportExpression. got=%T", stmt.Value)
+	testStringLiteral(assert, exp.Path, "lib.mk")
+}
+
+func TestAssignExpression(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lexer.New("x -= 5;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.AssignExpression)
+	if assert.True(ok, "exp is not ast.AssignExpression. got=%T", stmt.Expression) {
+		testIdentifier(assert, exp.Target, "x")
+		assert.Equal("-=", exp.Operator)
+		testIntegerLiteral(assert, exp.Value, 5)
+	}
+
+	p = New(lexer.New("1 = 2"))
+	p.ParseProgram()
+	if assert.Len(p.Errors(), 1) {
+		assert.Equal(CodeInvalidAssignmentTarget, p.Errors()[0].Code)
+	}
+}
+
+func TestMemberExpression(t *testing.T) {
+	assert := assert.New(t)
+	input := `math.abs`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MemberExpression)
+	assert.True(ok, "stmt.Expression is not ast.MemberExpression. got=%T", stmt.Expression)
+	testIdentifier(assert, exp.Left, "math")
+	testIdentifier(assert, exp.Member, "abs")
+
+	p = New(lexer.New(`math.1`))
+	p.ParseProgram()
+	if assert.NotEmpty(p.Errors()) {
+		assert.Equal("1:6: expected next token to be IDENTIFIER, got INT instead", p.Errors()[0].String())
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	assert := assert.New(t)
 	input := `fn(x, y) { x + y; }`
@@ -214,6 +405,172 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 10) {}", "fn(a, b = 10) "},
+		{"fn(a = 1, b = a + 1) {}", "fn(a = 1, b = (a + 1)) "},
+		{"fn(...rest) {}", "fn(...rest) "},
+		{"fn(first, second = 2, ...rest) {}", "fn(first, second = 2, ...rest) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		assert.Equal(tt.expected, program.String(), tt.input)
+	}
+
+	function := parseFunction(t, "fn(a, b = 10, ...c) {}")
+	testIdentifier(assert, function.Parameters[1], "b")
+	if assert.Len(function.Defaults, 2) {
+		assert.Nil(function.Defaults[0])
+		testIntegerLiteral(assert, function.Defaults[1], 10)
+	}
+	testIdentifier(assert, function.Rest, "c")
+
+	function = parseFunction(t, "fn(a, b) {}")
+	assert.Empty(function.Defaults)
+	assert.Nil(function.Rest)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, a) {}", "1:7: rest parameter must be the last one"},
+		{"fn(a = 1, b) {}", "1:11: parameter b without a default follows one with a default"},
+		{"fn(...) {}", "1:7: expected next token to be IDENTIFIER, got RPAREN instead"},
+		{"fn(1) {}", "1:4: expected next token to be IDENTIFIER, got INT instead"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if assert.NotEmpty(p.Errors(), tt.input) {
+			assert.Equal(tt.expected, p.Errors()[0].String(), tt.input)
+		}
+	}
+}
+
+func TestSpreadExpressions(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(a, ...b, ...c + d)", "f(a, ...b, ...(c + d))"},
+		{"[1, ...xs, 2]", "[1, ...xs, 2]"},
+		{"{...a, \"b\": 1, ...c}", "{...a, b:1, ...c}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		assert.Equal(tt.expected, program.String(), tt.input)
+	}
+
+	p := New(lexer.New("{...a}"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	hash := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+	if assert.Len(hash.Pairs, 1) {
+		spread, ok := hash.Pairs[0].Key.(*ast.SpreadExpression)
+		if assert.True(ok, "key is not *ast.SpreadExpression. got=%T", hash.Pairs[0].Key) {
+			testIdentifier(assert, spread.Value, "a")
+		}
+		assert.Nil(hash.Pairs[0].Value)
+	}
+
+	// Spreads only make sense inside a list
+	p = New(lexer.New("let a = ...b;"))
+	p.ParseProgram()
+	if assert.NotEmpty(p.Errors()) {
+		assert.Equal("1:9: no prefix parse function for ELLIPSIS found", p.Errors()[0].String())
+	}
+}
+
+func parseFunction(t *testing.T, input string) *ast.FunctionLiteral {
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	return program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+}
+
+func TestTailCalls(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `fn() {
+  a();
+  if (x) { return b() };
+  while (y) { return c(); d() };
+  try { return e() } catch (err) { i() };
+  if (z) { f() } else { g(h()) }
+}`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	tail := map[string]bool{}
+	collectCalls(stmt.Expression, tail)
+
+	assert.Equal(map[string]bool{
+		"a": false, "b": true, "c": true, "d": false, "e": false,
+		"i": false, "f": true, "g": true, "h": false,
+	}, tail)
+
+	// Outside functions nothing is a tail call
+	p = New(lexer.New("f()"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	assert.False(call.Tail)
+}
+
+// collectCalls records whether each call in node, by function name, is a tail
+// call.
+func collectCalls(node ast.Node, tail map[string]bool) {
+	switch node := node.(type) {
+	case *ast.FunctionLiteral:
+		collectCalls(node.Body, tail)
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			collectCalls(stmt, tail)
+		}
+	case *ast.ExpressionStatement:
+		collectCalls(node.Expression, tail)
+	case *ast.ReturnStatement:
+		collectCalls(node.ReturnValue, tail)
+	case *ast.WhileStatement:
+		collectCalls(node.Body, tail)
+	case *ast.IfExpression:
+		collectCalls(node.Then, tail)
+		if node.Else != nil {
+			collectCalls(node.Else, tail)
+		}
+	case *ast.TryExpression:
+		collectCalls(node.Block, tail)
+		collectCalls(node.Catch, tail)
+	case *ast.CallExpression:
+		tail[node.Function.String()] = node.Tail
+		for _, arg := range node.Arguments {
+			collectCalls(arg, tail)
+		}
+	}
+}
+
 func TestCallExpression(t *testing.T) {
 	assert := assert.New(t)
 	input := "add(1, 2 * 3, 4 + 5);"
@@ -274,6 +631,26 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	testLiteralExpression(assert, stmt.Expression, int64(5))
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	assert := assert.New(t)
+	input := "2.5e-1;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assert.Equal(1, len(program.Statements), "program has not enough statements")
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	assert.True(ok, "program.Statements[0] is not ast.ExpressionStatement")
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if assert.True(ok, "exp not *ast.FloatLiteral. got=%T", stmt.Expression) {
+		assert.Equal(0.25, literal.Value)
+		assert.Equal("2.5e-1", literal.TokenLiteral())
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	assert := assert.New(t)
 
@@ -446,6 +823,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * b / c",
 			"((a * b) / c)",
 		},
+		{
+			"a + 1 <= b == c >= d * 2",
+			"(((a + 1) <= b) == (c >= (d * 2)))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && c < d || !e",
+			"(((a == b) && (c < d)) || (!e))",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
 		{
 			"a + b / c",
 			"(a + (b / c))",
@@ -526,6 +919,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"math.abs(-a) * b",
+			"((math.abs)((-a)) * b)",
+		},
+		{
+			"a.b.c[0]",
+			"(((a.b).c)[0])",
+		},
+		{
+			"-1.5 * 2",
+			"((-1.5) * 2)",
+		},
+		{
+			"a = b = c + 1",
+			"(a = (b = (c + 1)))",
+		},
+		{
+			"a[i] += x || y",
+			"((a[i]) += (x || y))",
+		},
+		{
+			"a.b *= 2; c /= d - 1",
+			"((a.b) *= 2)(c /= (d - 1))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -539,6 +956,155 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "1:7: expected next token to be ASSIGN, got INT instead"},
+		{"let x = 1;\nlet = 2;", "2:5: expected next token to be IDENTIFIER, got ASSIGN instead"},
+		{"let y = );", "1:9: no prefix parse function for RPAREN found"},
+		{"\n\n   09", `3:4: could not parse "09" as integer`},
+		{"1e400", `1:1: could not parse "1e400" as float`},
+		{"let a = 1;\nf() = 2;", "2:5: cannot assign to f()"},
+		{"a + b -= 1", "1:7: cannot assign to (a + b)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if assert.NotEmpty(p.Errors(), tt.input) {
+			assert.Equal(tt.expected, p.Errors()[0].String(), tt.input)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lexer.New(`let add = fn(x, y { x + y };`)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if !assert.NotEmpty(errors) {
+		return
+	}
+
+	d := errors[0]
+	assert.Equal(SeverityError, d.Severity)
+	assert.Equal(CodeUnexpectedToken, d.Code)
+	assert.Equal("expected next token to be RPAREN, got LBRACE instead", d.Message)
+	assert.Equal([]token.TokenType{token.RPAREN}, d.Expected)
+	assert.Equal(token.LBRACE, d.Actual)
+	assert.Equal(token.Position{Line: 1, Column: 19}, d.Span.Start)
+	assert.Equal(token.Position{Line: 1, Column: 20}, d.Span.End)
+	assert.Equal(`add the missing ")"`, d.Hint)
+}
+
+func TestErrorRecovery(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			`let x 5;
+let = 10;
+let y = 838383;`,
+			[]string{
+				"1:7: expected next token to be ASSIGN, got INT instead",
+				"2:5: expected next token to be IDENTIFIER, got ASSIGN instead",
+			},
+			[]string{"let y = 838383;"},
+		},
+		{
+			`if (x { y }
+let ok = 1;
+add(1, 2 3);
+ok`,
+			[]string{
+				"1:7: expected next token to be RPAREN, got LBRACE instead",
+				"3:10: expected next token to be RPAREN, got INT instead",
+			},
+			[]string{"let ok = 1;", "ok"},
+		},
+		{
+			`let f = fn(x) {
+  let = 1;
+  let y = x +;
+  y
+};
+let g = fn() { 2 } let h = ;`,
+			[]string{
+				"2:7: expected next token to be IDENTIFIER, got ASSIGN instead",
+				"3:14: no prefix parse function for SEMICOLON found",
+				"6:28: no prefix parse function for SEMICOLON found",
+			},
+			[]string{"let f = fn(x) y;", "let g = fn() 2;"},
+		},
+		{
+			`let a = [1, 2;
+return a;`,
+			[]string{
+				"1:14: expected next token to be RBRACKET, got SEMICOLON instead",
+			},
+			[]string{"return a ; "},
+		},
+		// Braces opened before the error are skipped over as well
+		{
+			`let h = {"a" 1}; let c = 3;`,
+			[]string{
+				"1:14: expected next token to be COLON, got INT instead",
+			},
+			[]string{"let c = 3;"},
+		},
+		{
+			`let h = {"a": {"b" 2}, "c": 3}; let e = 5;`,
+			[]string{
+				"1:20: expected next token to be COLON, got INT instead",
+			},
+			[]string{"let e = 5;"},
+		},
+		{
+			`let f = fn() { let h = {"a" 1}; let c = 3; }; let d = 4;`,
+			[]string{
+				"1:29: expected next token to be COLON, got INT instead",
+			},
+			[]string{"let f = fn() let c = 3;;", "let d = 4;"},
+		},
+		{
+			`let f = fn() { let x = }; let d = 4;`,
+			[]string{
+				"1:24: no prefix parse function for RBRACE found",
+			},
+			[]string{"let f = fn() ;", "let d = 4;"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := []string{}
+		for _, d := range p.Errors() {
+			errors = append(errors, d.String())
+		}
+		assert.Equal(tt.expectedErrors, errors, tt.input)
+
+		statements := []string{}
+		for _, stmt := range program.Statements {
+			statements = append(statements, stmt.String())
+		}
+		assert.Equal(tt.expectedStatements, statements, tt.input)
+	}
+}
+
 func testLiteralExpression(assert *assert.Assertions, exp ast.Expression, expected interface{}) bool {
 	switch v := expected.(type) {
 	case int:
@@ -574,11 +1140,14 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	keys := []string{}
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		assert.True(ok)
-		testIntegerLiteral(assert, value, expected[literal.String()])
+		testIntegerLiteral(assert, pair.Value, expected[literal.String()])
+		keys = append(keys, literal.String())
 	}
+	assert.Equal([]string{"one", "two", "three"}, keys, "pairs are not in source order")
 }
 
 func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
@@ -602,10 +1171,10 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		3: "three",
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.IntegerLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.IntegerLiteral)
 		assert.True(ok)
-		testStringLiteral(assert, value, expected[literal.Value])
+		testStringLiteral(assert, pair.Value, expected[literal.Value])
 	}
 }
 
@@ -629,10 +1198,10 @@ func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 		false: 2,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.Boolean)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.Boolean)
 		assert.True(ok)
-		testIntegerLiteral(assert, value, expected[literal.Value])
+		testIntegerLiteral(assert, pair.Value, expected[literal.Value])
 	}
 }
 
@@ -676,12 +1245,12 @@ func testParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		assert.True(ok)
 		testFunc, ok := tests[literal.String()]
 		assert.True(ok)
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
@@ -743,8 +1312,8 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 
 	t.Errorf("parser had %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	for _, diagnostic := range errors {
+		t.Errorf("parser error: %q", diagnostic)
 	}
 	t.FailNow()
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
 	"monkey/evaluator"
 	"monkey/lexer"
@@ -42,7 +43,7 @@ func Start(in io.Reader, out io.Writer) {
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			printParserErrors(out, line, p.Errors())
 			continue
 		}
 
@@ -51,14 +52,55 @@ func Start(in io.Reader, out io.Writer) {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, err.StackTrace())
+		}
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, source string, diagnostics []parser.Diagnostic) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	PrintDiagnostics(out, source, diagnostics)
+}
+
+// PrintDiagnostics writes each diagnostic followed by the offending source
+// line with the problem underlined.
+func PrintDiagnostics(out io.Writer, source string, diagnostics []parser.Diagnostic) {
+	lines := strings.Split(source, "\n")
+	for _, d := range diagnostics {
+		io.WriteString(out, "\t"+d.String()+"\n")
+
+		if line := d.Span.Start.Line; line >= 1 && line <= len(lines) {
+			io.WriteString(out, "\t"+lines[line-1]+"\n")
+			io.WriteString(out, "\t"+caretLine(lines[line-1], d.Span)+"\n")
+		}
+
+		if d.Hint != "" {
+			io.WriteString(out, "\thint: "+d.Hint+"\n")
+		}
 	}
 }
+
+// caretLine underlines span within line, keeping tabs so the carets line up
+// with the source however wide the terminal renders them.
+func caretLine(line string, span parser.Span) string {
+	var out strings.Builder
+
+	for i := 0; i < span.Start.Column-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column > span.Start.Column {
+		width = span.End.Column - span.Start.Column
+	}
+	out.WriteString(strings.Repeat("^", width))
+
+	return out.String()
+}
@@ -0,0 +1,57 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParserErrorsAreUnderlined(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x 5;",
+			"\t1:7: expected next token to be ASSIGN, got INT instead\n" +
+				"\tlet x 5;\n" +
+				"\t      ^\n" +
+				"\thint: bindings take the form `let <name> = <value>;`\n",
+		},
+		{
+			`let let = "abc";`,
+			"\t1:5: expected next token to be IDENTIFIER, got LET instead\n" +
+				"\tlet let = \"abc\";\n" +
+				"\t    ^^^\n" +
+				"\thint: \"let\" is a keyword and can't be used as a name\n",
+		},
+		{
+			"\tlet y = ;",
+			"\t1:10: no prefix parse function for SEMICOLON found\n" +
+				"\t\tlet y = ;\n" +
+				"\t\t        ^\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		assert.Contains(out.String(), tt.expected)
+	}
+}
+
+func TestRuntimeErrorsPrintStackTrace(t *testing.T) {
+	assert := assert.New(t)
+	input := `let fail = fn() { -"monkey" }; let run = fn() { fail() }; run()`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	assert.Contains(out.String(), "ERROR: 1:19: unknown operator: -STRING\n"+
+		"\tat fail (1:49)\n"+
+		"\tat run (1:59)\n")
+}
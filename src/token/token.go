@@ -1,10 +1,39 @@
 package token
 
+import "fmt"
+
 type TokenType byte
 
 type Token struct {
 	Literal string
 	Type    TokenType
+	Pos     Position
+}
+
+// Position is a location in the source. Lines and columns start at 1, so the
+// zero value is an unknown position.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 //go:generate stringer -type=TokenType
@@ -15,6 +44,7 @@ const (
 	// Identifiers + literals
 	IDENTIFIER
 	INT
+	FLOAT
 	STRING
 
 	// Operators
@@ -24,16 +54,28 @@ const (
 	BANG
 	ASTERISK
 	SLASH
+	PERCENT
+
+	PLUS_ASSIGN
+	MINUS_ASSIGN
+	ASTERISK_ASSIGN
+	SLASH_ASSIGN
 
 	LT
 	GT
+	LT_EQ
+	GT_EQ
 	EQ
 	NOT_EQ
+	AND
+	OR
 
 	// Delimeters
 	COMMA
 	SEMICOLON
 	COLON
+	DOT
+	ELLIPSIS
 
 	LPAREN
 	RPAREN
@@ -50,16 +92,36 @@ const (
 	IF
 	ELSE
 	RETURN
+	THROW
+	TRY
+	CATCH
+	FINALLY
+	IMPORT
+	WHILE
+	FOR
+	IN
+	BREAK
+	CONTINUE
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"import":   IMPORT,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
 func LookupIdentifier(identifier string) TokenType {
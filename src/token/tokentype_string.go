@@ -12,38 +12,60 @@ func _() {
 	_ = x[EOF-1]
 	_ = x[IDENTIFIER-2]
 	_ = x[INT-3]
-	_ = x[STRING-4]
-	_ = x[ASSIGN-5]
-	_ = x[PLUS-6]
-	_ = x[MINUS-7]
-	_ = x[BANG-8]
-	_ = x[ASTERISK-9]
-	_ = x[SLASH-10]
-	_ = x[LT-11]
-	_ = x[GT-12]
-	_ = x[EQ-13]
-	_ = x[NOT_EQ-14]
-	_ = x[COMMA-15]
-	_ = x[SEMICOLON-16]
-	_ = x[COLON-17]
-	_ = x[LPAREN-18]
-	_ = x[RPAREN-19]
-	_ = x[LBRACE-20]
-	_ = x[RBRACE-21]
-	_ = x[LBRACKET-22]
-	_ = x[RBRACKET-23]
-	_ = x[FUNCTION-24]
-	_ = x[LET-25]
-	_ = x[TRUE-26]
-	_ = x[FALSE-27]
-	_ = x[IF-28]
-	_ = x[ELSE-29]
-	_ = x[RETURN-30]
+	_ = x[FLOAT-4]
+	_ = x[STRING-5]
+	_ = x[ASSIGN-6]
+	_ = x[PLUS-7]
+	_ = x[MINUS-8]
+	_ = x[BANG-9]
+	_ = x[ASTERISK-10]
+	_ = x[SLASH-11]
+	_ = x[PERCENT-12]
+	_ = x[PLUS_ASSIGN-13]
+	_ = x[MINUS_ASSIGN-14]
+	_ = x[ASTERISK_ASSIGN-15]
+	_ = x[SLASH_ASSIGN-16]
+	_ = x[LT-17]
+	_ = x[GT-18]
+	_ = x[LT_EQ-19]
+	_ = x[GT_EQ-20]
+	_ = x[EQ-21]
+	_ = x[NOT_EQ-22]
+	_ = x[AND-23]
+	_ = x[OR-24]
+	_ = x[COMMA-25]
+	_ = x[SEMICOLON-26]
+	_ = x[COLON-27]
+	_ = x[DOT-28]
+	_ = x[ELLIPSIS-29]
+	_ = x[LPAREN-30]
+	_ = x[RPAREN-31]
+	_ = x[LBRACE-32]
+	_ = x[RBRACE-33]
+	_ = x[LBRACKET-34]
+	_ = x[RBRACKET-35]
+	_ = x[FUNCTION-36]
+	_ = x[LET-37]
+	_ = x[TRUE-38]
+	_ = x[FALSE-39]
+	_ = x[IF-40]
+	_ = x[ELSE-41]
+	_ = x[RETURN-42]
+	_ = x[THROW-43]
+	_ = x[TRY-44]
+	_ = x[CATCH-45]
+	_ = x[FINALLY-46]
+	_ = x[IMPORT-47]
+	_ = x[WHILE-48]
+	_ = x[FOR-49]
+	_ = x[IN-50]
+	_ = x[BREAK-51]
+	_ = x[CONTINUE-52]
 }
 
-const _TokenType_name = "ILLEGALEOFIDENTIFIERINTSTRINGASSIGNPLUSMINUSBANGASTERISKSLASHLTGTEQNOT_EQCOMMASEMICOLONCOLONLPARENRPARENLBRACERBRACELBRACKETRBRACKETFUNCTIONLETTRUEFALSEIFELSERETURN"
+const _TokenType_name = "ILLEGALEOFIDENTIFIERINTFLOATSTRINGASSIGNPLUSMINUSBANGASTERISKSLASHPERCENTPLUS_ASSIGNMINUS_ASSIGNASTERISK_ASSIGNSLASH_ASSIGNLTGTLT_EQGT_EQEQNOT_EQANDORCOMMASEMICOLONCOLONDOTELLIPSISLPARENRPARENLBRACERBRACELBRACKETRBRACKETFUNCTIONLETTRUEFALSEIFELSERETURNTHROWTRYCATCHFINALLYIMPORTWHILEFORINBREAKCONTINUE"
 
-var _TokenType_index = [...]uint8{0, 7, 10, 20, 23, 29, 35, 39, 44, 48, 56, 61, 63, 65, 67, 73, 78, 87, 92, 98, 104, 110, 116, 124, 132, 140, 143, 147, 152, 154, 158, 164}
+var _TokenType_index = [...]uint16{0, 7, 10, 20, 23, 28, 34, 40, 44, 49, 53, 61, 66, 73, 84, 96, 111, 123, 125, 127, 132, 137, 139, 145, 148, 150, 155, 164, 169, 172, 180, 186, 192, 198, 204, 212, 220, 228, 231, 235, 240, 242, 246, 252, 257, 260, 265, 272, 278, 283, 286, 288, 293, 301}
 
 func (i TokenType) String() string {
 	if i >= TokenType(len(_TokenType_index)-1) {
@@ -0,0 +1,461 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/code"
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/object"
+)
+
+// StackSize is the initial size of the stack, which grows as calls need it.
+// How deep calls can nest is limited by evaluator.DefaultMaxCallDepth.
+const StackSize = 2048
+const GlobalsSize = 65536
+
+var (
+	NULL  = evaluator.NULL
+	TRUE  = evaluator.TRUE
+	FALSE = evaluator.FALSE
+)
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpLessThan:     "<",
+	code.OpGreaterThan:  ">",
+	code.OpLessEqual:    "<=",
+	code.OpGreaterEqual: ">=",
+}
+
+type VM struct {
+	constants   []object.Object
+	globals     []object.Object
+	globalNames []string
+	builtins    []*object.Builtin
+
+	stack []object.Object
+	sp    int // Always points to the next free slot. Top of stack is stack[sp-1]
+
+	frames      []*Frame
+	framesIndex int
+
+	// The value of the last top-level statement, nil if it has none
+	lastValue object.Object
+
+	// Set when the program failed with a Monkey error, which ends execution
+	// the same way it ends evaluation in the tree-walking evaluator
+	err *object.Error
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithGlobalsStore(bytecode, make([]object.Object, GlobalsSize))
+}
+
+// NewWithGlobalsStore reuses globals across runs, so successive programs
+// compiled with the same symbol table can see each other's bindings.
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := []*Frame{mainFrame}
+
+	names := evaluator.BuiltinNames()
+	builtins := make([]*object.Builtin, len(names))
+	for i, name := range names {
+		builtins[i], _ = evaluator.LookupBuiltin(name)
+	}
+
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     globals,
+		globalNames: bytecode.GlobalNames,
+		builtins:    builtins,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// LastPoppedStackElem returns the result of the program: the value of the
+// last statement, or the error that stopped execution. Like the result of
+// evaluator.Eval it is nil if the last statement has no value.
+func (vm *VM) LastPoppedStackElem() object.Object {
+	if vm.err != nil {
+		return vm.err
+	}
+	return vm.lastValue
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// Run executes the bytecode. The returned error reports a failure of the VM
+// itself; errors raised by the program are available as its result.
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		var result object.Object
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			result = vm.constants[constIndex]
+
+		case code.OpPop:
+			popped := vm.pop()
+			if vm.framesIndex == 1 {
+				vm.lastValue = popped
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+			code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpGreaterThan,
+			code.OpLessEqual, code.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			result = evaluator.EvalInfix(infixOperators[op], left, right)
+
+		case code.OpTrue:
+			result = TRUE
+
+		case code.OpFalse:
+			result = FALSE
+
+		case code.OpNull:
+			result = NULL
+
+		case code.OpBang:
+			result = evaluator.EvalPrefix("!", vm.pop())
+
+		case code.OpMinus:
+			result = evaluator.EvalPrefix("-", vm.pop())
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !evaluator.IsTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.stack[vm.sp-1]
+			if evaluator.IsTruthy(condition) == (op == code.OpJumpTruthyOrPop) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.globals[globalIndex] = vm.pop()
+			if vm.framesIndex == 1 {
+				vm.lastValue = nil
+			}
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			result = vm.globals[globalIndex]
+			if result == nil {
+				result = newError("identifier not found: %s", vm.globalNames[globalIndex])
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			result = vm.stack[frame.basePointer+int(localIndex)]
+			if result == nil {
+				result = newError("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			result = vm.builtins[builtinIndex]
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			result = vm.currentFrame().cl.Free[freeIndex]
+
+		case code.OpCurrentClosure:
+			result = vm.currentFrame().cl
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp = vm.sp - numElements
+
+			result = &object.Array{Elements: elements}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			result = vm.buildHash(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result = evaluator.EvalIndex(left, index)
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			result = vm.executeCall(int(numArgs))
+
+		case code.OpTailCall:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			callee, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure)
+			if ok && vm.framesIndex > 1 {
+				result = vm.tailCallClosure(callee, numArgs)
+			} else {
+				result = vm.executeCall(numArgs)
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			if vm.framesIndex == 1 {
+				// A top-level return ends the program with its value
+				vm.lastValue = returnValue
+				return nil
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			result = returnValue
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			result = NULL
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			var err error
+			result, err = vm.buildClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("unhandled opcode %s", def.Name)
+		}
+
+		if errObj, ok := result.(*object.Error); ok {
+			vm.err = errObj
+			return nil
+		}
+
+		if result != nil {
+			vm.push(result)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) {
+	vm.growStack(vm.sp + 1)
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+}
+
+// growStack makes room for size elements on the stack.
+func (vm *VM) growStack(size int) {
+	if size <= len(vm.stack) {
+		return
+	}
+
+	stack := make([]object.Object, max(size, 2*len(vm.stack)))
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) object.Object {
+	hash := &object.Hash{}
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		if !object.Hashable(key) {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(key, value)
+	}
+
+	return hash
+}
+
+// executeCall calls the callee sitting below numArgs arguments on the stack.
+// Builtins run immediately and return their result, closures push a frame and
+// return nil so the loop continues in the callee.
+func (vm *VM) executeCall(numArgs int) object.Object {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return newError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) object.Object {
+	if numArgs != cl.Fn.NumParameters {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			numArgs, cl.Fn.NumParameters)
+	}
+
+	// The main frame isn't a call
+	if vm.framesIndex > evaluator.DefaultMaxCallDepth {
+		return newError("maximum recursion depth exceeded")
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	vm.pushFrame(frame)
+	vm.enterFrame(frame, numArgs)
+
+	return nil
+}
+
+// tailCallClosure calls cl in the current frame, in place of the function
+// that would return its result.
+func (vm *VM) tailCallClosure(cl *object.Closure, numArgs int) object.Object {
+	if numArgs != cl.Fn.NumParameters {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			numArgs, cl.Fn.NumParameters)
+	}
+
+	// Move the callee and its arguments to where the caller's are
+	frame := vm.currentFrame()
+	copy(vm.stack[frame.basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+
+	frame.cl = cl
+	frame.ip = -1
+	vm.enterFrame(frame, numArgs)
+
+	return nil
+}
+
+// enterFrame makes room for the locals of frame, whose numArgs arguments are
+// on the stack.
+func (vm *VM) enterFrame(frame *Frame, numArgs int) {
+	vm.growStack(frame.basePointer + frame.cl.Fn.NumLocals)
+	vm.sp = frame.basePointer + frame.cl.Fn.NumLocals
+
+	// Locals left over from earlier frames must not be seen before they are
+	// set
+	clear(vm.stack[frame.basePointer+numArgs : vm.sp])
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) object.Object {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+	result := builtin.Call(args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+func (vm *VM) buildClosure(constIndex int, numFree int) (object.Object, error) {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return nil, fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp = vm.sp - numFree
+
+	return &object.Closure{Fn: function, Free: free}, nil
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"testing"
+
+	"monkey/ast"
+	"monkey/compiler"
+	"monkey/evaluator/evaltest"
+	"monkey/lexer"
+	"monkey/parser"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	runVmTests(t, evaltest.IntegerExpressions)
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	runVmTests(t, evaltest.BigIntegerExpressions)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	runVmTests(t, evaltest.FloatExpressions)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	runVmTests(t, evaltest.BooleanExpressions)
+}
+
+func TestBangOperator(t *testing.T) {
+	runVmTests(t, evaltest.BangOperator)
+}
+
+func TestStringExpressions(t *testing.T) {
+	runVmTests(t, evaltest.StringExpressions)
+}
+
+func TestComparisons(t *testing.T) {
+	runVmTests(t, evaltest.Comparisons)
+}
+
+func TestStructuralEquality(t *testing.T) {
+	runVmTests(t, evaltest.StructuralEquality)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	runVmTests(t, evaltest.LogicalOperators)
+}
+
+func TestConditionals(t *testing.T) {
+	runVmTests(t, evaltest.IfElseExpressions)
+}
+
+func TestReturnStatements(t *testing.T) {
+	runVmTests(t, evaltest.ReturnStatements)
+}
+
+func TestLetStatements(t *testing.T) {
+	runVmTests(t, evaltest.LetStatements)
+}
+
+func TestErrors(t *testing.T) {
+	runVmTests(t, evaltest.ErrorHandling)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	runVmTests(t, evaltest.FunctionApplication)
+}
+
+func TestClosures(t *testing.T) {
+	runVmTests(t, evaltest.Closures)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	runVmTests(t, evaltest.RecursiveFunctions)
+}
+
+func TestTailCalls(t *testing.T) {
+	runVmTests(t, evaltest.TailCalls)
+}
+
+func TestArrayExpressions(t *testing.T) {
+	runVmTests(t, evaltest.ArrayExpressions)
+}
+
+func TestHashExpressions(t *testing.T) {
+	runVmTests(t, evaltest.HashExpressions)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	runVmTests(t, evaltest.BuiltinFunctions)
+}
+
+// runVmTests runs programs the evaluator is tested with too, so both
+// backends must agree on every one of them.
+func runVmTests(t *testing.T, tests []evaltest.Case) {
+	assert := assert.New(t)
+
+	for _, tt := range tests {
+		program := parse(tt.Input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		assert.NoError(err, "compiler error for %q", tt.Input)
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		assert.NoError(err, "vm error for %q", tt.Input)
+
+		evaltest.Check(assert, tt, vm.LastPoppedStackElem())
+	}
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}